@@ -0,0 +1,127 @@
+package weatherlandscape
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"weatherlandscape/p_weather"
+)
+
+const (
+	DITHER_NONE = iota
+	DITHER_FLOYDSTEINBERG
+	DITHER_ATKINSON
+	DITHER_BAYER
+)
+
+func ParseDither(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return DITHER_NONE, nil
+	case "floyd-steinberg", "fs":
+		return DITHER_FLOYDSTEINBERG, nil
+	case "atkinson":
+		return DITHER_ATKINSON, nil
+	case "bayer", "ordered":
+		return DITHER_BAYER, nil
+	}
+	return DITHER_NONE, fmt.Errorf("unknown dithering '%s'", name)
+}
+
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Quantize reduces img to the given number of evenly spaced grey levels:
+// 2 for a 1-bit panel, 4 for a 4-grey panel, 256 keeps full grey. With
+// tricolor the red pixels are kept as they are and left out of the
+// dithering, and the result is an RGBA image rather than a grey one.
+func Quantize(img image.Image, levels, method int, tricolor bool) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewGray(b)
+
+	buf := make([]float64, w*h)
+	red := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			buf[y*w+x] = float64(color.GrayModel.Convert(c).(color.Gray).Y)
+			red[y*w+x] = tricolor && p_weather.IsRed(c)
+		}
+	}
+
+	if levels < 2 {
+		levels = 2
+	}
+	step := 255.0 / float64(levels-1)
+	nearest := func(v float64) float64 {
+		q := float64(int(v/step+0.5)) * step
+		if q < 0 {
+			return 0
+		}
+		if q > 255 {
+			return 255
+		}
+		return q
+	}
+	spread := func(x, y int, e float64) {
+		if x < 0 || x >= w || y >= h {
+			return
+		}
+		buf[y*w+x] += e
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if red[y*w+x] {
+				continue
+			}
+			old := buf[y*w+x]
+			var q float64
+			switch method {
+			case DITHER_BAYER:
+				q = nearest(old + (bayer4[y%4][x%4]+0.5)/16*step - step/2)
+			default:
+				q = nearest(old)
+			}
+			out.SetGray(b.Min.X+x, b.Min.Y+y, color.Gray{uint8(q)})
+
+			e := old - q
+			switch method {
+			case DITHER_FLOYDSTEINBERG:
+				spread(x+1, y, e*7/16)
+				spread(x-1, y+1, e*3/16)
+				spread(x, y+1, e*5/16)
+				spread(x+1, y+1, e*1/16)
+			case DITHER_ATKINSON:
+				spread(x+1, y, e/8)
+				spread(x+2, y, e/8)
+				spread(x-1, y+1, e/8)
+				spread(x, y+1, e/8)
+				spread(x+1, y+1, e/8)
+				spread(x, y+2, e/8)
+			}
+		}
+	}
+	if !tricolor {
+		return out
+	}
+
+	rgba := image.NewRGBA(b)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if red[y*w+x] {
+				rgba.Set(b.Min.X+x, b.Min.Y+y, color.RGBA{255, 0, 0, 255})
+			} else {
+				rgba.Set(b.Min.X+x, b.Min.Y+y, out.GrayAt(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+	}
+	return rgba
+}
@@ -0,0 +1,120 @@
+package weatherlandscape
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseDither(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    int
+		wantErr bool
+	}{
+		{"", DITHER_NONE, false},
+		{"none", DITHER_NONE, false},
+		{"Floyd-Steinberg", DITHER_FLOYDSTEINBERG, false},
+		{"fs", DITHER_FLOYDSTEINBERG, false},
+		{"atkinson", DITHER_ATKINSON, false},
+		{"bayer", DITHER_BAYER, false},
+		{"ordered", DITHER_BAYER, false},
+		{"halftone", DITHER_NONE, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDither(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDither(%q) = %d, %v, want %d, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQuantizeLevels(t *testing.T) {
+	tests := []struct {
+		levels int
+		grey   uint8
+		want   uint8
+	}{
+		{2, 0x7F, 0},
+		{2, 0x80, 255},
+		{1, 0x80, 255}, // fewer than 2 levels is 1-bit
+		{4, 32, 0},
+		{4, 48, 85},
+		{4, 130, 170},
+		{4, 200, 170},
+		{4, 230, 255},
+		{256, 123, 123},
+	}
+	for _, tt := range tests {
+		img := image.NewGray(image.Rect(0, 0, 1, 1))
+		img.SetGray(0, 0, color.Gray{tt.grey})
+		out := Quantize(img, tt.levels, DITHER_NONE, false).(*image.Gray)
+		if got := out.GrayAt(0, 0).Y; got != tt.want {
+			t.Errorf("Quantize(%d, %d levels) = %d, want %d", tt.grey, tt.levels, got, tt.want)
+		}
+	}
+}
+
+// TestQuantizeDither checks that dithering a flat mid grey to 1 bit
+// keeps roughly half the pixels white, where plain rounding turns
+// them all black.
+func TestQuantizeDither(t *testing.T) {
+	tests := []struct {
+		method   int
+		min, max float64
+	}{
+		{DITHER_NONE, 0, 0},
+		{DITHER_FLOYDSTEINBERG, 0.4, 0.6},
+		{DITHER_ATKINSON, 0.4, 0.6},
+		{DITHER_BAYER, 0.4, 0.6},
+	}
+	img := image.NewGray(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = 0x7F
+	}
+	for _, tt := range tests {
+		out := Quantize(img, 2, tt.method, false).(*image.Gray)
+		white := 0
+		for _, p := range out.Pix {
+			if p != 0 && p != 255 {
+				t.Fatalf("method %d: grey level %d left", tt.method, p)
+			}
+			if p == 255 {
+				white++
+			}
+		}
+		if f := float64(white) / float64(len(out.Pix)); f < tt.min || f > tt.max {
+			t.Errorf("method %d: %.2f of the pixels are white, want %.2f to %.2f", tt.method, f, tt.min, tt.max)
+		}
+	}
+}
+
+func TestQuantizeTricolor(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, color.Gray{0x20})
+	img.Set(2, 0, color.RGBA{0xF0, 0x10, 0x10, 255})
+	img.Set(3, 0, color.Gray{0xE0})
+
+	tests := []struct {
+		tricolor bool
+		want     []color.Color
+	}{
+		{true, []color.Color{red, color.Black, red, color.White}},
+		{false, []color.Color{color.Black, color.Black, color.Black, color.White}},
+	}
+	for _, tt := range tests {
+		out := Quantize(img, 2, DITHER_NONE, tt.tricolor)
+		if _, ok := out.(*image.Gray); ok == tt.tricolor {
+			t.Errorf("tricolor %v: Quantize returned %T", tt.tricolor, out)
+		}
+		for x, want := range tt.want {
+			r, g, b, _ := out.At(x, 0).RGBA()
+			wr, wg, wb, _ := want.RGBA()
+			if r != wr || g != wg || b != wb {
+				t.Errorf("tricolor %v: pixel %d is %v, want %v", tt.tricolor, x, out.At(x, 0), want)
+			}
+		}
+	}
+}
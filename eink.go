@@ -0,0 +1,125 @@
+package weatherlandscape
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+
+	"weatherlandscape/p_weather"
+)
+
+// einkPalette is the 1-bit palette of the E-Ink bitmap, index 0 black
+// and 1 white, the way the panel buffer takes its bits.
+var einkPalette = color.Palette{color.Black, color.White}
+
+// EinkImage turns a landscape image the way the 2.9" panel takes it:
+// rotated clockwise and flipped top to bottom, 128 pixels wide and 296
+// high. The panel has one bit a pixel, so anything dark, red included,
+// is black.
+func EinkImage(img image.Image) *image.Paletted {
+	b := img.Bounds()
+	out := image.NewPaletted(image.Rect(0, 0, b.Dy(), b.Dx()), einkPalette)
+	for y := 0; y < b.Dx(); y++ {
+		for x := 0; x < b.Dy(); x++ {
+			r, g, bl, _ := img.At(b.Max.X-1-y, b.Max.Y-1-x).RGBA()
+			if (r+g+bl)/3 >= 0x8000 {
+				out.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return out
+}
+
+// EncodeEinkBMP writes the E-Ink bitmap as a 1 bit per pixel BMP. The
+// firmware copies the pixel data straight into the panel buffer, which
+// image/bmp cannot give: it writes paletted images at 8 bits per pixel.
+// Rows are padded to 4 bytes and stored bottom-up, as in any BMP.
+func EncodeEinkBMP(w io.Writer, img *image.Paletted) error {
+	b := img.Bounds()
+	rowBytes := ((b.Dx() + 31) / 32) * 4
+	const offset = 14 + 40 + 2*4
+	size := offset + rowBytes*b.Dy()
+
+	hdr := make([]byte, offset)
+	copy(hdr, "BM")
+	binary.LittleEndian.PutUint32(hdr[2:], uint32(size))
+	binary.LittleEndian.PutUint32(hdr[10:], offset)
+	binary.LittleEndian.PutUint32(hdr[14:], 40)
+	binary.LittleEndian.PutUint32(hdr[18:], uint32(b.Dx()))
+	binary.LittleEndian.PutUint32(hdr[22:], uint32(b.Dy()))
+	binary.LittleEndian.PutUint16(hdr[26:], 1) // planes
+	binary.LittleEndian.PutUint16(hdr[28:], 1) // bits per pixel
+	binary.LittleEndian.PutUint32(hdr[34:], uint32(rowBytes*b.Dy()))
+	binary.LittleEndian.PutUint32(hdr[46:], 2) // colours used
+	for i, c := range einkPalette {
+		r, g, bl, _ := c.RGBA()
+		copy(hdr[54+4*i:], []byte{byte(bl >> 8), byte(g >> 8), byte(r >> 8), 0})
+	}
+
+	data := make([]byte, rowBytes*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		row := data[(b.Dy()-1-y)*rowBytes:]
+		for x := 0; x < b.Dx(); x++ {
+			if img.ColorIndexAt(b.Min.X+x, b.Min.Y+y) != 0 {
+				row[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// EinkPlanes packs a landscape image into the black and the red bit-plane
+// of a 2.9" B/W/R panel. The panel is 128 pixels wide and 296 high, so the
+// image is turned the same way as the E-Ink bitmap: rotated clockwise and
+// flipped top to bottom. Bits go MSB first, 0 marks a black (or red) pixel.
+func EinkPlanes(img image.Image) (black, red []byte) {
+	b := img.Bounds()
+	w, h := b.Dy(), b.Dx()
+	rowBytes := (w + 7) / 8
+	black = make([]byte, rowBytes*h)
+	red = make([]byte, rowBytes*h)
+	for i := range black {
+		black[i] = 0xFF
+		red[i] = 0xFF
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.At(b.Max.X-1-y, b.Max.Y-1-x)
+			r, g, bl, _ := c.RGBA()
+			i := y*rowBytes + x/8
+			bit := byte(0x80 >> uint(x%8))
+			switch {
+			case p_weather.IsRed(c):
+				red[i] &^= bit
+			case (r+g+bl)/3 < 0x8000:
+				black[i] &^= bit
+			}
+		}
+	}
+	return black, red
+}
+
+// EinkGrey2 packs a landscape image for a 4-grey 2.9" panel, turned the
+// same way as the E-Ink bitmap, at 2 bits per pixel: four pixels a byte,
+// MSB first, 0 for black up to 3 for white.
+func EinkGrey2(img image.Image) []byte {
+	b := img.Bounds()
+	w, h := b.Dy(), b.Dx()
+	rowBytes := (w + 3) / 4
+	out := make([]byte, rowBytes*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			grey := color.GrayModel.Convert(img.At(b.Max.X-1-y, b.Max.Y-1-x)).(color.Gray).Y
+			level := byte((int(grey) + 42) / 85)
+			out[y*rowBytes+x/4] |= level << uint(6-2*(x%4))
+		}
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package weatherlandscape
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// whiteImage is a 10x9 landscape image, so the panel planes are 9
+// pixels (2 bytes) wide and 10 rows high.
+func whiteImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 9))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func TestEinkPlanes(t *testing.T) {
+	tests := []struct {
+		name  string
+		x, y  int
+		c     color.Color
+		plane string // which plane has the pixel cleared, "" for none
+		index int
+		bit   byte
+	}{
+		{"black in the last corner", 9, 8, color.Black, "black", 0, 0x80},
+		{"red in the first corner", 0, 0, color.RGBA{255, 0, 0, 255}, "red", 19, 0x80},
+		{"dark grey is black", 5, 7, color.Gray{0x40}, "black", 8, 0x40},
+		{"light grey is white", 5, 7, color.Gray{0xC0}, "", 0, 0},
+		{"dark red is black", 5, 7, color.RGBA{0x60, 0, 0, 255}, "black", 8, 0x40},
+	}
+	for _, tt := range tests {
+		img := whiteImage()
+		img.Set(tt.x, tt.y, tt.c)
+		black, red := EinkPlanes(img)
+		if len(black) != 20 || len(red) != 20 {
+			t.Fatalf("%s: planes are %d and %d bytes, want 20", tt.name, len(black), len(red))
+		}
+		for i := range black {
+			wantBlack, wantRed := byte(0xFF), byte(0xFF)
+			if i == tt.index && tt.plane == "black" {
+				wantBlack &^= tt.bit
+			}
+			if i == tt.index && tt.plane == "red" {
+				wantRed &^= tt.bit
+			}
+			if black[i] != wantBlack || red[i] != wantRed {
+				t.Errorf("%s: byte %d is %08b/%08b, want %08b/%08b", tt.name, i, black[i], red[i], wantBlack, wantRed)
+			}
+		}
+	}
+}
+
+func TestEinkImage(t *testing.T) {
+	img := whiteImage()
+	img.Set(9, 8, color.Black)
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	out := EinkImage(img)
+	if b := out.Bounds(); b.Dx() != 9 || b.Dy() != 10 {
+		t.Fatalf("EinkImage is %dx%d, want 9x10", b.Dx(), b.Dy())
+	}
+	// red is black on the 1-bit panel
+	for _, p := range []image.Point{{0, 0}, {8, 9}} {
+		if got := out.ColorIndexAt(p.X, p.Y); got != 0 {
+			t.Errorf("pixel %v has index %d, want black", p, got)
+		}
+	}
+	if got := out.ColorIndexAt(4, 4); got != 1 {
+		t.Errorf("pixel 4,4 has index %d, want white", got)
+	}
+}
+
+func TestEncodeEinkBMP(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 296, 128))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	img.Set(295, 127, color.Black) // panel pixel 0,0
+	img.Set(0, 0, color.Black)     // panel pixel 127,295
+
+	var buf bytes.Buffer
+	if err := EncodeEinkBMP(&buf, EinkImage(img)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	const size = 62 + 16*296
+	if len(data) != size {
+		t.Fatalf("BMP is %d bytes, want %d", len(data), size)
+	}
+	le := binary.LittleEndian
+	if string(data[:2]) != "BM" || le.Uint32(data[2:]) != size || le.Uint32(data[10:]) != 62 {
+		t.Errorf("file header % X", data[:14])
+	}
+	if w, h := le.Uint32(data[18:]), le.Uint32(data[22:]); w != 128 || h != 296 {
+		t.Errorf("BMP is %dx%d, want 128x296", w, h)
+	}
+	if bits := le.Uint16(data[28:]); bits != 1 {
+		t.Errorf("biBitCount is %d, want 1", bits)
+	}
+	if !bytes.Equal(data[54:62], []byte{0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0}) {
+		t.Errorf("palette % X, want black then white", data[54:62])
+	}
+
+	// rows are bottom-up: the last one stored is panel row 0
+	pixels := data[62:]
+	if got := pixels[295*16]; got != 0x7F {
+		t.Errorf("first byte of panel row 0 is %08b", got)
+	}
+	if got := pixels[15]; got != 0xFE {
+		t.Errorf("last byte of panel row 295 is %08b", got)
+	}
+	if got := pixels[100*16+5]; got != 0xFF {
+		t.Errorf("white pixels are %08b", got)
+	}
+}
+
+func TestEinkPlanesPattern(t *testing.T) {
+	// a 3x10 landscape, '#' black and 'r' red; on the panel it is 10
+	// pixels (2 bytes) wide and 3 rows high, row 0 being the right
+	// column read from the bottom up
+	rows := []string{
+		"#.r",
+		"...",
+		"..#",
+		"...",
+		"...",
+		"...",
+		"...",
+		"...",
+		"...",
+		"##.",
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 3, len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case '#':
+				img.Set(x, y, color.Black)
+			case 'r':
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			default:
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	black, red := EinkPlanes(img)
+	wantBlack := []byte{0xFE, 0xFF, 0x7F, 0xFF, 0x7F, 0xBF}
+	wantRed := []byte{0xFF, 0xBF, 0xFF, 0xFF, 0xFF, 0xFF}
+	if !bytes.Equal(black, wantBlack) {
+		t.Errorf("black plane % X, want % X", black, wantBlack)
+	}
+	if !bytes.Equal(red, wantRed) {
+		t.Errorf("red plane % X, want % X", red, wantRed)
+	}
+
+	// the planes are the E-Ink bitmap's pixels
+	eink := EinkImage(img)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 10; x++ {
+			r, g, b, _ := eink.At(x, y).RGBA()
+			dark := (r+g+b)/3 < 0x8000
+			set := black[y*2+x/8]&(0x80>>uint(x%8)) == 0 || red[y*2+x/8]&(0x80>>uint(x%8)) == 0
+			if dark != set {
+				t.Errorf("panel pixel %d,%d: bitmap dark %v, plane bit set %v", x, y, dark, set)
+			}
+		}
+	}
+}
+
+func TestEinkGrey2(t *testing.T) {
+	// a 2x5 landscape turns into a panel 5 pixels (2 bytes) wide and 2
+	// rows high, row 0 being the right column read from the bottom up
+	greys := [][2]uint8{
+		{0x00, 0xFF},
+		{0x55, 0xAA},
+		{0xAA, 0x55},
+		{0xFF, 0x00},
+		{0x30, 0xD0},
+	}
+	img := image.NewGray(image.Rect(0, 0, 2, len(greys)))
+	for y, row := range greys {
+		for x, g := range row {
+			img.SetGray(x, y, color.Gray{g})
+		}
+	}
+	// panel row 0 holds levels 2 0 1 2 3, row 1 holds 1 3 2 1 0
+	want := []byte{0x86, 0xC0, 0x79, 0x00}
+	if got := EinkGrey2(img); !bytes.Equal(got, want) {
+		t.Errorf("EinkGrey2 = % X, want % X", got, want)
+	}
+
+	if n := len(EinkGrey2(image.NewGray(image.Rect(0, 0, 296, 128)))); n != 128*296/4 {
+		t.Errorf("panel image packs into %d bytes, want %d", n, 128*296/4)
+	}
+}
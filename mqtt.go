@@ -0,0 +1,171 @@
+package weatherlandscape
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"weatherlandscape/p_weather"
+)
+
+const (
+	MQTT_RENDERED = "/rendered"
+	MQTT_STATUS   = "/status"
+	SUMMARY_HOURS = 24 // hours ahead of the current observation the event sums up
+)
+
+// MQTTBridge publishes an event for every rendered landscape and turns
+// readings from sensor topics into the sensor file the renderer reads,
+// the same one the HTTP push endpoint writes.
+type MQTTBridge struct {
+	client     mqtt.Client
+	prefix     string
+	sensorFile string
+	lock       sync.Mutex
+}
+
+// RenderEvent is the payload published under <prefix>/rendered. The
+// weather summary covers a fixed SUMMARY_HOURS from the current
+// observation, not whatever span the layout fits into the landscape, in
+// the units the series was fetched in.
+type RenderEvent struct {
+	ETag          string    `json:"etag"`
+	Time          time.Time `json:"time"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	InkRatio      float64   `json:"ink_ratio"`
+	RedRatio      float64   `json:"red_ratio"`
+	Units         string    `json:"units"`
+	Temp          float64   `json:"temp"`
+	TempMin       float64   `json:"temp_min"`
+	TempMax       float64   `json:"temp_max"`
+	Precipitation float64   `json:"precipitation"`
+}
+
+// NewRenderEvent describes the rendered img and the series it was drawn
+// from.
+func NewRenderEvent(etag string, img image.Image, ws *p_weather.WeatherSeries) *RenderEvent {
+	b := img.Bounds()
+	ev := &RenderEvent{ETag: etag, Time: time.Now(), Lat: ws.Latitude, Lon: ws.Longitude,
+		Width: b.Dx(), Height: b.Dy(), Units: ws.Units.String()}
+	ev.InkRatio, ev.RedRatio = InkRatios(img)
+	if curr := ws.GetCurr(); curr != nil {
+		until := curr.T.Add(SUMMARY_HOURS * time.Hour)
+		ev.Temp = curr.Temp
+		ev.TempMin, ev.TempMax = ws.GetTempRange(until)
+		ev.Precipitation = ws.GetPrecipitation(until)
+	}
+	return ev
+}
+
+// NewMQTTBridge connects to broker and subscribes to the sensor topics,
+// which map a topic to the quantity it carries: temp, humidity or
+// pressure. The client ID is the prefix and the host name, so that
+// several servers can share a broker.
+func NewMQTTBridge(broker, prefix string, sensorTopics map[string]string, sensorFile string) (*MQTTBridge, error) {
+	b := &MQTTBridge{prefix: strings.TrimSuffix(prefix, "/"), sensorFile: sensorFile}
+	host, err := os.Hostname()
+	if err != nil {
+		host = strconv.Itoa(os.Getpid())
+	}
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID(b.prefix + "-" + host).SetAutoReconnect(true)
+	opts.SetWill(b.prefix+MQTT_STATUS, "offline", 0, true)
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		c.Publish(b.prefix+MQTT_STATUS, 0, true, "online")
+		for topic, quantity := range sensorTopics {
+			quantity := quantity
+			c.Subscribe(topic, 0, func(c mqtt.Client, msg mqtt.Message) {
+				if err := b.saveReading(quantity, msg.Payload()); err != nil {
+					fmt.Printf("MQTT %s: %s\n", msg.Topic(), err)
+				}
+			})
+		}
+	})
+	b.client = mqtt.NewClient(opts)
+	if t := b.client.Connect(); t.Wait() && t.Error() != nil {
+		return nil, t.Error()
+	}
+	return b, nil
+}
+
+// saveReading merges one quantity into the sensor file. Payloads are a
+// bare number, as Home Assistant publishes states, or JSON with the
+// value under the quantity's name.
+func (b *MQTTBridge) saveReading(quantity string, payload []byte) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+	if err != nil {
+		var obj map[string]interface{}
+		if json.Unmarshal(payload, &obj) != nil {
+			return fmt.Errorf("not a number: %q", payload)
+		}
+		f, ok := obj[quantity].(float64)
+		if !ok {
+			return fmt.Errorf("no '%s' in %s", quantity, payload)
+		}
+		v = f
+	}
+	r := &p_weather.SensorReading{T: time.Now()}
+	switch quantity {
+	case "temp":
+		r.Temp = &v
+	case "humidity":
+		r.Humidity = &v
+	case "pressure":
+		r.Pressure = &v
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	reading := map[string]interface{}{}
+	if data, err := os.ReadFile(b.sensorFile); err == nil {
+		json.Unmarshal(data, &reading)
+	}
+	reading[quantity] = v
+	reading["time"] = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(reading)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.sensorFile, data, 0644)
+}
+
+func (b *MQTTBridge) PublishRender(ev *RenderEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	t := b.client.Publish(b.prefix+MQTT_RENDERED, 0, true, data)
+	t.Wait()
+	return t.Error()
+}
+
+// InkRatios tells what share of the image is black and what is red.
+func InkRatios(img image.Image) (ink, red float64) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.At(x, y)
+			r, g, bl, _ := c.RGBA()
+			switch {
+			case p_weather.IsRed(c):
+				red++
+			case (r+g+bl)/3 < 0x8000:
+				ink++
+			}
+		}
+	}
+	n := float64(bounds.Dx() * bounds.Dy())
+	return ink / n, red / n
+}
@@ -0,0 +1,193 @@
+package weatherlandscape
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"weatherlandscape/p_weather"
+)
+
+// startBroker runs an in-process broker on a free port and returns it
+// with the URL to connect to.
+func startBroker(t *testing.T) (*mqttserver.Server, string) {
+	t.Helper()
+	server := mqttserver.New(&mqttserver.Options{InlineClient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	server.AddHook(new(auth.AllowHook), nil)
+	tcp := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	if err := server.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, "tcp://" + tcp.Address()
+}
+
+// watch collects the payloads the broker sees on topic.
+func watch(t *testing.T, server *mqttserver.Server, topic string) chan packets.Packet {
+	t.Helper()
+	ch := make(chan packets.Packet, 10)
+	err := server.Subscribe(topic, 1, func(cl *mqttserver.Client, sub packets.Subscription, pk packets.Packet) {
+		ch <- pk
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func receive(t *testing.T, ch chan packets.Packet, payload string) packets.Packet {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case pk := <-ch:
+			if payload == "" || string(pk.Payload) == payload {
+				return pk
+			}
+		case <-timeout:
+			t.Fatalf("no message %q", payload)
+		}
+	}
+}
+
+func newTestBridge(t *testing.T, broker string, sensorTopics map[string]string) *MQTTBridge {
+	t.Helper()
+	b, err := NewMQTTBridge(broker, "wl/", sensorTopics, filepath.Join(t.TempDir(), "sensor.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.client.Disconnect(0) })
+	return b
+}
+
+func TestMQTTPublishRender(t *testing.T) {
+	server, broker := startBroker(t)
+	rendered := watch(t, server, "wl"+MQTT_RENDERED)
+	b := newTestBridge(t, broker, nil)
+
+	host, _ := os.Hostname()
+	if _, ok := server.Clients.Get("wl-" + host); !ok {
+		t.Errorf("no client wl-%s on the broker", host)
+	}
+
+	now := time.Now()
+	ws := &p_weather.WeatherSeries{Latitude: 52.2, Longitude: 21, Units: p_weather.UNITS_METRIC, F: []*p_weather.WeatherInfo{
+		{T: now, Temp: 10},
+		{T: now.Add(3 * time.Hour), Temp: 14, Rain: 1},
+		{T: now.Add(6 * time.Hour), Temp: 6, Snow: 0.5},
+		{T: now.Add(27 * time.Hour), Temp: 30, Rain: 10},
+	}}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.Black)
+	img.Set(1, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(2, 0, color.White)
+	img.Set(3, 0, color.White)
+	if err := b.PublishRender(NewRenderEvent(`"abc"`, img, ws)); err != nil {
+		t.Fatal(err)
+	}
+
+	pk := receive(t, rendered, "")
+	if !pk.FixedHeader.Retain {
+		t.Errorf("render event not retained")
+	}
+	var ev RenderEvent
+	if err := json.Unmarshal(pk.Payload, &ev); err != nil {
+		t.Fatal(err)
+	}
+	want := RenderEvent{ETag: `"abc"`, Lat: 52.2, Lon: 21, Width: 4, Height: 1, InkRatio: 0.25, RedRatio: 0.25,
+		Units: "metric", Temp: 10, TempMin: 6, TempMax: 14, Precipitation: 1.5}
+	ev.Time = time.Time{}
+	if ev != want {
+		t.Errorf("render event %+v, want %+v", ev, want)
+	}
+}
+
+func TestMQTTSensorTopics(t *testing.T) {
+	server, broker := startBroker(t)
+	// retained, so the bridge gets them as it subscribes
+	server.Publish("home/temp", []byte("21.5"), true, 0)
+	server.Publish("home/humidity", []byte(`{"humidity": 40, "battery": 90}`), true, 0)
+	server.Publish("home/pressure", []byte("high"), true, 0)
+	b := newTestBridge(t, broker, map[string]string{"home/temp": "temp", "home/humidity": "humidity", "home/pressure": "pressure"})
+
+	sensor := &p_weather.FileSensor{Path: b.sensorFile}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r, err := sensor.Latest()
+		if err == nil && r != nil && r.Temp != nil && r.Humidity != nil {
+			if *r.Temp != 21.5 || *r.Humidity != 40 || r.Pressure != nil {
+				t.Errorf("reading %v/%v/%v, want 21.5/40/none", *r.Temp, *r.Humidity, r.Pressure)
+			}
+			if time.Since(r.T) > time.Minute {
+				t.Errorf("reading time %s", r.T)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sensor file not written: %v, %v", r, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestMQTTSaveReading(t *testing.T) {
+	b := &MQTTBridge{sensorFile: filepath.Join(t.TempDir(), "sensor.json")}
+	tests := []struct {
+		quantity, payload string
+		wantErr           bool
+	}{
+		{"temp", " 20.5\n", false},
+		{"humidity", `{"humidity": 55}`, false},
+		{"pressure", `{"temp": 1}`, true},
+		{"pressure", "high", true},
+		{"temp", `{"temp": 19}`, false},
+		{"temp", "NaN", true},
+		{"pressure", "-Inf", true},
+		{"temp", "500", true},
+		{"humidity", `{"humidity": 300}`, true},
+	}
+	for _, tt := range tests {
+		if err := b.saveReading(tt.quantity, []byte(tt.payload)); (err != nil) != tt.wantErr {
+			t.Errorf("saveReading(%s, %q) error %v, want error %v", tt.quantity, tt.payload, err, tt.wantErr)
+		}
+	}
+	r, err := (&p_weather.FileSensor{Path: b.sensorFile}).Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *r.Temp != 19 || *r.Humidity != 55 || r.Pressure != nil {
+		t.Errorf("merged reading %v/%v/%v, want 19/55/none", *r.Temp, *r.Humidity, r.Pressure)
+	}
+}
+
+func TestMQTTWill(t *testing.T) {
+	server, broker := startBroker(t)
+	status := watch(t, server, "wl"+MQTT_STATUS)
+	newTestBridge(t, broker, nil)
+	receive(t, status, "online")
+
+	host, _ := os.Hostname()
+	cl, ok := server.Clients.Get("wl-" + host)
+	if !ok {
+		t.Fatalf("no client wl-%s on the broker", host)
+	}
+	cl.Stop(errors.New("connection lost"))
+	if pk := receive(t, status, "offline"); !pk.FixedHeader.Retain {
+		t.Errorf("will not retained")
+	}
+}
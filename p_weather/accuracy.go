@@ -0,0 +1,160 @@
+package p_weather
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	PROVIDER_OWM      = "openweathermap"
+	ACCURACY_MAX_LEAD = 5 * 24 * time.Hour // as far as forecasts reach
+)
+
+// AccuracyStats compares the forecasts of one provider made LeadHours
+// ahead with the conditions observed later. Temperatures are in the
+// units the data was loaded in.
+type AccuracyStats struct {
+	Provider  string  `json:"provider"`
+	LeadHours int     `json:"lead_hours"`
+	Samples   int     `json:"samples"`
+	TempMAE   float64 `json:"temp_mae"`
+	CloudMAE  float64 `json:"cloud_mae"`
+	// precipitation forecast against precipitation observed
+	PrecipHits        int     `json:"precip_hits"`
+	PrecipMisses      int     `json:"precip_misses"`
+	PrecipFalseAlarms int     `json:"precip_false_alarms"`
+	PrecipCorrectNone int     `json:"precip_correct_none"`
+	PrecipAccuracy    float64 `json:"precip_accuracy"`
+
+	tempErr, cloudErr float64
+}
+
+func isPrecipitating(f *WeatherInfo) bool {
+	return f.ID < 700 || f.Rain+f.Snow > 0
+}
+
+func (a *AccuracyStats) add(fc, obs *WeatherInfo) {
+	a.Samples++
+	a.tempErr += math.Abs(fc.Temp - obs.Temp)
+	a.cloudErr += math.Abs(float64(fc.Clouds - obs.Clouds))
+	switch p, o := isPrecipitating(fc), isPrecipitating(obs); {
+	case p && o:
+		a.PrecipHits++
+	case o:
+		a.PrecipMisses++
+	case p:
+		a.PrecipFalseAlarms++
+	default:
+		a.PrecipCorrectNone++
+	}
+	n := float64(a.Samples)
+	a.TempMAE = a.tempErr / n
+	a.CloudMAE = a.cloudErr / n
+	a.PrecipAccuracy = float64(a.PrecipHits+a.PrecipCorrectNone) / n
+}
+
+// Accuracy matches the observation of every archived snapshot against
+// what the earlier snapshots of the same archive forecast for that time,
+// grouped by provider and by lead time in forecast periods. It reads the
+// whole archives, so it is meant for reports rather than for every
+// fetch.
+func Accuracy(archives ...*Archive) ([]*AccuracyStats, error) {
+	type key struct {
+		provider string
+		lead     int
+	}
+	stats := map[key]*AccuracyStats{}
+	for _, arc := range archives {
+		times, err := arc.Times()
+		if err != nil {
+			return nil, err
+		}
+		snapshots := make([][]*WeatherInfo, len(times))
+		for i, t := range times {
+			ws, err := arc.Load(t)
+			if err != nil {
+				fmt.Println("Accuracy:", err)
+				continue
+			}
+			snapshots[i] = ws.F
+		}
+
+		period := time.Duration(FORECAST_PERIOD_HOURS) * time.Hour
+		for i := range times {
+			if len(snapshots[i]) == 0 {
+				continue
+			}
+			obs := snapshots[i][0]
+			for j := i - 1; j >= 0 && obs.T.Sub(times[j]) <= ACCURACY_MAX_LEAD; j-- {
+				if len(snapshots[j]) < 2 {
+					continue
+				}
+				fc := nearestForecast(snapshots[j][1:], obs.T)
+				if fc == nil || absDuration(fc.T.Sub(obs.T)) > period/2 {
+					continue
+				}
+				lead := int(obs.T.Sub(times[j]).Hours()) / FORECAST_PERIOD_HOURS * FORECAST_PERIOD_HOURS
+				if lead < 0 {
+					continue
+				}
+				k := key{arc.Name(), lead}
+				a, ok := stats[k]
+				if !ok {
+					a = &AccuracyStats{Provider: arc.Name(), LeadHours: lead}
+					stats[k] = a
+				}
+				a.add(fc, obs)
+			}
+		}
+	}
+
+	list := []*AccuracyStats{}
+	for _, a := range stats {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Provider != list[j].Provider {
+			return list[i].Provider < list[j].Provider
+		}
+		return list[i].LeadHours < list[j].LeadHours
+	})
+	return list, nil
+}
+
+// nearestForecast finds the entry of a forecast, sorted by time, closest
+// to t.
+func nearestForecast(F []*WeatherInfo, t time.Time) *WeatherInfo {
+	i := sort.Search(len(F), func(i int) bool { return !F[i].T.Before(t) })
+	var best *WeatherInfo
+	for _, k := range []int{i - 1, i} {
+		if k < 0 || k >= len(F) {
+			continue
+		}
+		if best == nil || absDuration(F[k].T.Sub(t)) < absDuration(best.T.Sub(t)) {
+			best = F[k]
+		}
+	}
+	return best
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func PrintAccuracy(w io.Writer, stats []*AccuracyStats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "provider\tlead h\tsamples\ttemp MAE\tcloud MAE %\tprecip hit\tmiss\tfalse\tnone\tprecip correct %\t")
+	for _, a := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.0f\t%d\t%d\t%d\t%d\t%.0f\t\n",
+			a.Provider, a.LeadHours, a.Samples, a.TempMAE, a.CloudMAE,
+			a.PrecipHits, a.PrecipMisses, a.PrecipFalseAlarms, a.PrecipCorrectNone, 100*a.PrecipAccuracy)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,114 @@
+package p_weather
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccuracy(t *testing.T) {
+	t0 := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	h := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+	snapshots := []*WeatherSeries{
+		{F: []*WeatherInfo{
+			{T: h(0), ID: 800, Temp: 10},
+			{T: h(3), ID: 500, Temp: 14, Clouds: 50},
+			{T: h(6), ID: 800, Temp: 16, Clouds: 100},
+		}},
+		{F: []*WeatherInfo{
+			{T: h(3), ID: 800, Temp: 12, Clouds: 20},
+			{T: h(6), ID: 500, Temp: 15, Clouds: 90},
+		}},
+		{F: []*WeatherInfo{
+			{T: h(6), ID: 501, Temp: 15, Clouds: 80},
+		}},
+	}
+	a := newTestArchive(t)
+	for i, ws := range snapshots {
+		if err := a.store(h(3*i), ws); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := Accuracy(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AccuracyStats{
+		{Provider: "fake", LeadHours: 3, Samples: 2, TempMAE: 1, CloudMAE: 20,
+			PrecipHits: 1, PrecipFalseAlarms: 1, PrecipAccuracy: 0.5},
+		{Provider: "fake", LeadHours: 6, Samples: 1, TempMAE: 1, CloudMAE: 20,
+			PrecipMisses: 1, PrecipAccuracy: 0},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("%d stats, want %d", len(stats), len(want))
+	}
+	for i, w := range want {
+		got := *stats[i]
+		got.tempErr, got.cloudErr = 0, 0
+		if got != w {
+			t.Errorf("stats %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestAccuracyStatsAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		fc, obs WeatherInfo
+		field   func(*AccuracyStats) int
+	}{
+		{"hit", WeatherInfo{ID: 500}, WeatherInfo{ID: 800, Rain: 0.2}, func(a *AccuracyStats) int { return a.PrecipHits }},
+		{"miss", WeatherInfo{ID: 800}, WeatherInfo{ID: 601}, func(a *AccuracyStats) int { return a.PrecipMisses }},
+		{"false alarm", WeatherInfo{ID: 800, Snow: 1}, WeatherInfo{ID: 801}, func(a *AccuracyStats) int { return a.PrecipFalseAlarms }},
+		{"correct none", WeatherInfo{ID: 804}, WeatherInfo{ID: 800}, func(a *AccuracyStats) int { return a.PrecipCorrectNone }},
+	}
+	for _, tt := range tests {
+		a := &AccuracyStats{}
+		a.add(&tt.fc, &tt.obs)
+		if tt.field(a) != 1 || a.Samples != 1 {
+			t.Errorf("%s: counted as %+v", tt.name, a)
+		}
+	}
+}
+
+func TestNearestForecast(t *testing.T) {
+	t0 := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	F := []*WeatherInfo{{T: t0}, {T: t0.Add(3 * time.Hour)}, {T: t0.Add(6 * time.Hour)}}
+	tests := []struct {
+		at   time.Duration
+		want int
+	}{
+		{-time.Hour, 0},
+		{time.Hour, 0},
+		{2 * time.Hour, 1},
+		{3 * time.Hour, 1},
+		{5 * time.Hour, 2},
+		{10 * time.Hour, 2},
+	}
+	for _, tt := range tests {
+		if got := nearestForecast(F, t0.Add(tt.at)); got != F[tt.want] {
+			t.Errorf("nearestForecast(%s) = %s, want %s", tt.at, got.T, F[tt.want].T)
+		}
+	}
+	if got := nearestForecast(nil, t0); got != nil {
+		t.Errorf("nearestForecast of no forecast = %v", got)
+	}
+}
+
+func TestPrintAccuracy(t *testing.T) {
+	var b strings.Builder
+	PrintAccuracy(&b, []*AccuracyStats{{Provider: "owm", LeadHours: 3, Samples: 10,
+		PrecipHits: 2, PrecipMisses: 1, PrecipFalseAlarms: 3, PrecipCorrectNone: 4, PrecipAccuracy: 0.6}})
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("printed %q", b.String())
+	}
+	head, row := strings.Fields(lines[0]), strings.Fields(lines[1])
+	if got := strings.Join(head[len(head)-5:], " "); got != "false none precip correct %" {
+		t.Errorf("header ends %q", got)
+	}
+	if got := strings.Join(row[len(row)-5:], " "); got != "2 1 3 4 60" {
+		t.Errorf("row ends %q", got)
+	}
+}
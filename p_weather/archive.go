@@ -0,0 +1,157 @@
+package p_weather
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ARCHIVE_DIR         = "archive"
+	ARCHIVE_TIME_FORMAT = "20060102T150405Z"
+	ARCHIVE_EXT         = ".json"
+)
+
+// Archive keeps a timestamped copy of every series its provider fetches,
+// so past displays can be replayed and old forecasts checked against
+// what was observed later. Snapshots go to
+// <rootdir>/archive/<provider>/<placekey>/.
+type Archive struct {
+	Provider      Provider
+	Rootdir       string
+	PLACEKEY      string
+	KEEP_DAYS     int
+	MAX_SNAPSHOTS int
+}
+
+func NewArchive(provider Provider, rootdir, placekey string) *Archive {
+	return &Archive{
+		Provider:      provider,
+		Rootdir:       rootdir,
+		PLACEKEY:      placekey,
+		KEEP_DAYS:     30, // 0 turns the archive off
+		MAX_SNAPSHOTS: 5000,
+	}
+}
+
+func (a *Archive) Name() string {
+	return a.Provider.Name()
+}
+
+func (a *Archive) dir() string {
+	return filepath.Join(a.Rootdir, ARCHIVE_DIR, a.Provider.Name(), a.PLACEKEY)
+}
+
+func (a *Archive) path(t time.Time) string {
+	return filepath.Join(a.dir(), t.UTC().Format(ARCHIVE_TIME_FORMAT)+ARCHIVE_EXT)
+}
+
+// Fetch passes the provider's series through and archives it.
+func (a *Archive) Fetch() (*WeatherSeries, error) {
+	ws, err := a.Provider.Fetch()
+	if err != nil {
+		return ws, err
+	}
+	if err := a.store(time.Now(), ws); err != nil {
+		fmt.Println("Archive:", err)
+	}
+	return ws, nil
+}
+
+// store saves ws as fetched at t, unless it is the same series as the
+// latest snapshot, as it is when the provider answers from its cache.
+// Snapshots past the retention limits are dropped.
+func (a *Archive) store(t time.Time, ws *WeatherSeries) error {
+	if a.KEEP_DAYS <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(ws)
+	if err != nil {
+		return err
+	}
+	times, err := a.Times()
+	if err != nil {
+		return err
+	}
+	if len(times) > 0 {
+		if last, err := os.ReadFile(a.path(times[len(times)-1])); err == nil && bytes.Equal(last, data) {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(a.dir(), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(a.path(t), data, 0644); err != nil {
+		return err
+	}
+	return a.prune(t)
+}
+
+// Times lists the fetch times of the archived snapshots, oldest first.
+func (a *Archive) Times() ([]time.Time, error) {
+	files, err := filepath.Glob(filepath.Join(a.dir(), "*"+ARCHIVE_EXT))
+	if err != nil {
+		return nil, err
+	}
+	times := []time.Time{}
+	for _, fn := range files {
+		stamp := strings.TrimSuffix(filepath.Base(fn), ARCHIVE_EXT)
+		t, err := time.Parse(ARCHIVE_TIME_FORMAT, stamp)
+		if err != nil {
+			continue
+		}
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times, nil
+}
+
+func (a *Archive) prune(now time.Time) error {
+	times, err := a.Times()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-time.Duration(a.KEEP_DAYS) * 24 * time.Hour)
+	for i, t := range times {
+		tooMany := a.MAX_SNAPSHOTS > 0 && len(times)-i > a.MAX_SNAPSHOTS
+		if !t.Before(cutoff) && !tooMany {
+			break
+		}
+		os.Remove(a.path(t))
+	}
+	return nil
+}
+
+// Load reads the snapshot fetched at taken.
+func (a *Archive) Load(taken time.Time) (*WeatherSeries, error) {
+	data, err := os.ReadFile(a.path(taken))
+	if err != nil {
+		return nil, err
+	}
+	ws := &WeatherSeries{}
+	if err := json.Unmarshal(data, ws); err != nil {
+		return nil, fmt.Errorf("%s: %w", a.path(taken), err)
+	}
+	return ws, nil
+}
+
+// At loads the last snapshot fetched at or before at, the data the
+// display was showing then, and returns when it was fetched.
+func (a *Archive) At(at time.Time) (*WeatherSeries, time.Time, error) {
+	times, err := a.Times()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	i := sort.Search(len(times), func(i int) bool { return times[i].After(at) })
+	if i == 0 {
+		return nil, time.Time{}, fmt.Errorf("no archived %s forecast before %s", a.Name(), at.Format(time.RFC3339))
+	}
+	ws, err := a.Load(times[i-1])
+	return ws, times[i-1], err
+}
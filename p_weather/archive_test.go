@@ -0,0 +1,128 @@
+package p_weather
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeProvider returns a fixed series.
+type fakeProvider struct {
+	name string
+	ws   *WeatherSeries
+	err  error
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Fetch() (*WeatherSeries, error) {
+	return p.ws, p.err
+}
+
+func newTestArchive(t *testing.T) *Archive {
+	return NewArchive(&fakeProvider{name: "fake"}, t.TempDir(), "PLACE")
+}
+
+// fillArchive writes an empty snapshot for every time.
+func fillArchive(t *testing.T, a *Archive, times []time.Time) {
+	if err := os.MkdirAll(a.dir(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range times {
+		if err := os.WriteFile(a.path(at), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArchivePrune(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name         string
+		keepDays     int
+		maxSnapshots int
+		ages         []time.Duration
+		want         int
+	}{
+		{"all recent", 30, 5000, []time.Duration{3 * day, 2 * day, 0}, 3},
+		{"drops old ones", 2, 5000, []time.Duration{5 * day, 3 * day, day, 0}, 2},
+		{"keeps the cutoff", 2, 5000, []time.Duration{2 * day, 0}, 2},
+		{"caps the count", 30, 2, []time.Duration{4 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour}, 2},
+		{"no cap", 30, 0, []time.Duration{4 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour}, 4},
+		{"age and count", 2, 1, []time.Duration{5 * day, time.Hour, 0}, 1},
+	}
+	for _, tt := range tests {
+		a := newTestArchive(t)
+		a.KEEP_DAYS, a.MAX_SNAPSHOTS = tt.keepDays, tt.maxSnapshots
+		times := []time.Time{}
+		for _, age := range tt.ages {
+			times = append(times, now.Add(-age))
+		}
+		fillArchive(t, a, times)
+
+		if err := a.prune(now); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := a.Times()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("%s: %d snapshots left, want %d", tt.name, len(got), tt.want)
+			continue
+		}
+		// the newest ones stay
+		for i, at := range got {
+			if want := times[len(times)-tt.want+i]; !at.Equal(want) {
+				t.Errorf("%s: snapshot %d is %s, want %s", tt.name, i, at, want)
+			}
+		}
+	}
+}
+
+func TestArchiveFetch(t *testing.T) {
+	ws := &WeatherSeries{Latitude: 52.2, Longitude: 21.0, Units: UNITS_IMPERIAL,
+		F: []*WeatherInfo{{T: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), ID: 500, Rain: 0.1, Temp: 50, Units: UNITS_IMPERIAL}}}
+	p := &fakeProvider{name: "fake", ws: ws}
+	a := NewArchive(p, t.TempDir(), "PLACE")
+
+	for i := 0; i < 2; i++ {
+		if got, err := a.Fetch(); err != nil || got != ws {
+			t.Fatalf("Fetch() = %v, %v, want the provider's series", got, err)
+		}
+	}
+	times, _ := a.Times()
+	if len(times) != 1 {
+		t.Errorf("the same series was archived %d times", len(times))
+	}
+
+	ws.F[0].Temp = 52
+	if err := a.store(times[0].Add(time.Hour), ws); err != nil {
+		t.Fatal(err)
+	}
+	times, _ = a.Times()
+	if len(times) != 2 {
+		t.Fatalf("%d snapshots after a change, want 2", len(times))
+	}
+
+	got, taken, err := a.At(times[1].Add(time.Minute))
+	if err != nil || !taken.Equal(times[1]) {
+		t.Fatalf("At() fetched %s, %v, want %s", taken, err, times[1])
+	}
+	if f := got.F[0]; got.Units != UNITS_IMPERIAL || f.Temp != 52 || f.Rain != 0.1 || f.ID != 500 {
+		t.Errorf("At() loaded %+v, %+v", got, f)
+	}
+	if _, _, err := a.At(times[0].Add(-time.Second)); err == nil {
+		t.Errorf("At() before the first snapshot did not fail")
+	}
+
+	a.KEEP_DAYS = 0
+	ws.F[0].Temp = 54
+	a.store(times[1].Add(time.Hour), ws)
+	if times, _ = a.Times(); len(times) != 2 {
+		t.Errorf("archived with KEEP_DAYS 0")
+	}
+}
@@ -0,0 +1,145 @@
+package p_weather
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed sprite/*.png sprite/pack.json
+var DEFAULT_SPRITES embed.FS
+
+const DEFAULT_SPRITES_DIR = "sprite"
+
+// SpriteAtlas holds every sprite of a pack decoded once, indexed by
+// "name_NN". Sprites from the embedded default pack are replaced by
+// files of the same name found in the override directory, the manifest
+// by the directory's own pack.json.
+type SpriteAtlas struct {
+	Pack   *SpritePack
+	images map[string]image.Image
+	errs   []error
+}
+
+var (
+	atlasCache = map[string]*SpriteAtlas{}
+	atlasLock  sync.Mutex
+)
+
+func spriteKey(name string, index int) string {
+	return name + "_" + formatIndex(index)
+}
+
+// LoadSpriteAtlas returns the atlas for the override directory dir,
+// decoding it on first use only. An empty dir gives the embedded pack.
+// A pack that does not validate is an error.
+func LoadSpriteAtlas(dir string) (*SpriteAtlas, error) {
+	atlasLock.Lock()
+	defer atlasLock.Unlock()
+
+	if a, ok := atlasCache[dir]; ok {
+		return a, a.Validate()
+	}
+
+	a := &SpriteAtlas{images: map[string]image.Image{}}
+	sub, _ := fs.Sub(DEFAULT_SPRITES, DEFAULT_SPRITES_DIR)
+	a.loadFS(sub, "embedded")
+	a.loadPack(sub, "embedded")
+	if dir != "" {
+		if _, err := os.Stat(dir); err == nil {
+			a.loadFS(os.DirFS(dir), dir)
+			if _, err := os.Stat(filepath.Join(dir, PACK_MANIFEST)); err == nil {
+				a.loadPack(os.DirFS(dir), dir)
+			}
+		} else {
+			a.errs = append(a.errs, fmt.Errorf("sprite directory '%s': %w", dir, err))
+		}
+	}
+
+	if a.Pack == nil {
+		a.errs = append(a.errs, errors.New("no sprite pack manifest"))
+		a.Pack = &SpritePack{Roles: map[string]*SpriteRole{}}
+		a.Pack.parseColors()
+	}
+	atlasCache[dir] = a
+	return a, a.Validate()
+}
+
+func (a *SpriteAtlas) loadFS(fsys fs.FS, origin string) {
+	files, err := fs.Glob(fsys, "*.png")
+	if err != nil {
+		a.errs = append(a.errs, err)
+		return
+	}
+	for _, fn := range files {
+		img, err := decodeSprite(fsys, fn)
+		if err != nil {
+			a.errs = append(a.errs, fmt.Errorf("%s: %s: %w", origin, fn, err))
+			continue
+		}
+		a.images[strings.TrimSuffix(fn, filepath.Ext(fn))] = img
+	}
+}
+
+func (a *SpriteAtlas) loadPack(fsys fs.FS, origin string) {
+	p, err := LoadSpritePack(fsys)
+	if err != nil {
+		a.errs = append(a.errs, fmt.Errorf("%s: %w", origin, err))
+		return
+	}
+	a.Pack = p
+}
+
+func decodeSprite(fsys fs.FS, fn string) (image.Image, error) {
+	file, err := fsys.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, errors.New("empty image")
+	}
+	return img, nil
+}
+
+func (a *SpriteAtlas) Get(name string, index int) image.Image {
+	return a.images[spriteKey(name, index)]
+}
+
+// Validate reports malformed files found while loading, roles missing
+// from the manifest and every sprite a role refers to that is missing
+// from the atlas.
+func (a *SpriteAtlas) Validate() error {
+	errs := append([]error{}, a.errs...)
+	for _, role := range REQUIRED_ROLES {
+		if _, ok := a.Pack.Roles[role]; !ok {
+			errs = append(errs, fmt.Errorf("missing role '%s'", role))
+		}
+	}
+	roles := []string{}
+	for role := range a.Pack.Roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		r := a.Pack.Roles[role]
+		for _, index := range r.Index {
+			if a.Get(r.Sprite, index) == nil {
+				errs = append(errs, fmt.Errorf("role '%s': missing sprite '%s'", role, spriteKey(r.Sprite, index)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package p_weather
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePNG(t *testing.T, fn string, w, h int) {
+	t.Helper()
+	file, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writePack writes the embedded manifest to dir, changed by edit.
+func writePack(t *testing.T, dir string, edit func(roles map[string]interface{})) {
+	t.Helper()
+	data, err := fs.ReadFile(DEFAULT_SPRITES, DEFAULT_SPRITES_DIR+"/"+PACK_MANIFEST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pack map[string]interface{}
+	if err := json.Unmarshal(data, &pack); err != nil {
+		t.Fatal(err)
+	}
+	edit(pack["roles"].(map[string]interface{}))
+	data, _ = json.Marshal(pack)
+	if err := os.WriteFile(filepath.Join(dir, PACK_MANIFEST), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSpriteAtlas(t *testing.T) {
+	if _, err := LoadSpriteAtlas(""); err != nil {
+		t.Fatalf("embedded pack: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(dir string)
+		wantErr string // "" for a pack that loads
+	}{
+		{"empty override", func(dir string) {}, ""},
+		{"sprite override", func(dir string) { writePNG(t, filepath.Join(dir, "sun_00.png"), 3, 2) }, ""},
+		{"manifest without a required role", func(dir string) {
+			writePack(t, dir, func(roles map[string]interface{}) { delete(roles, "moon") })
+		}, "missing role 'moon'"},
+		{"role with a missing sprite", func(dir string) {
+			writePack(t, dir, func(roles map[string]interface{}) {
+				roles["sun"] = map[string]interface{}{"sprite": "sun", "index": []int{0, 7}}
+			})
+		}, "role 'sun': missing sprite 'sun_07'"},
+		{"malformed sprite", func(dir string) {
+			os.WriteFile(filepath.Join(dir, "moon_00.png"), []byte("not a png"), 0644)
+		}, "moon_00.png"},
+		{"malformed manifest", func(dir string) {
+			os.WriteFile(filepath.Join(dir, PACK_MANIFEST), []byte("{"), 0644)
+		}, PACK_MANIFEST},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		tt.setup(dir)
+		a, err := LoadSpriteAtlas(dir)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error %v, want one about %s", tt.name, err, tt.wantErr)
+		}
+		if _, err := NewSprites(dir, image.NewRGBA(image.Rect(0, 0, 296, 128))); (err != nil) != (tt.wantErr != "") {
+			t.Errorf("%s: NewSprites error %v", tt.name, err)
+		}
+
+		if again, _ := LoadSpriteAtlas(dir); again != a {
+			t.Errorf("%s: atlas decoded twice", tt.name)
+		}
+	}
+
+	if _, err := LoadSpriteAtlas(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("missing override directory loaded")
+	}
+}
+
+func TestSpriteAtlasOverride(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "sun_00.png"), 3, 2)
+	a, err := LoadSpriteAtlas(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := a.Get("sun", 0).Bounds(); b.Dx() != 3 || b.Dy() != 2 {
+		t.Errorf("sun_00 is %v, want the 3x2 override", b)
+	}
+	embedded, _ := LoadSpriteAtlas("")
+	if moon := a.Get("moon", 0); moon == nil || moon.Bounds() != embedded.Get("moon", 0).Bounds() {
+		t.Errorf("moon_00 not taken from the embedded pack")
+	}
+	if a.Get("sun", 0) == embedded.Get("sun", 0) {
+		t.Errorf("override changed the embedded atlas")
+	}
+}
@@ -0,0 +1,222 @@
+package p_weather
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+// Canvas is what Sprites draws on. Sprites come recoloured to the output
+// colours, transparent where nothing is to be drawn.
+type Canvas interface {
+	Bounds() image.Rectangle
+	SetPixel(x, y int, col color.Color)
+	BlitSprite(img image.Image, x, y int)
+	DrawPath(p *Path, col color.Color, dashed bool)
+}
+
+// TextCanvas is a Canvas that renders text itself instead of getting it
+// as digit sprites.
+type TextCanvas interface {
+	DrawText(text string, x, y, align int, col color.Color)
+}
+
+const (
+	PATH_MOVE = iota
+	PATH_LINE
+	PATH_CURVE
+)
+
+type pathSeg struct {
+	op  int
+	pts [3][2]float64
+}
+
+// Path is a line through pixel coordinates made of straight and cubic
+// Bézier segments.
+type Path struct {
+	segs []pathSeg
+}
+
+func (p *Path) MoveTo(x, y float64) {
+	p.segs = append(p.segs, pathSeg{op: PATH_MOVE, pts: [3][2]float64{{x, y}}})
+}
+
+func (p *Path) LineTo(x, y float64) {
+	p.segs = append(p.segs, pathSeg{op: PATH_LINE, pts: [3][2]float64{{x, y}}})
+}
+
+func (p *Path) CurveTo(x1, y1, x2, y2, x, y float64) {
+	p.segs = append(p.segs, pathSeg{op: PATH_CURVE, pts: [3][2]float64{{x1, y1}, {x2, y2}, {x, y}}})
+}
+
+// String returns the path as SVG path data.
+func (p *Path) String() string {
+	var b strings.Builder
+	for _, s := range p.segs {
+		switch s.op {
+		case PATH_MOVE:
+			fmt.Fprintf(&b, "M%.1f %.1f", s.pts[0][0], s.pts[0][1])
+		case PATH_LINE:
+			fmt.Fprintf(&b, "L%.1f %.1f", s.pts[0][0], s.pts[0][1])
+		case PATH_CURVE:
+			fmt.Fprintf(&b, "C%.1f %.1f %.1f %.1f %.1f %.1f",
+				s.pts[0][0], s.pts[0][1], s.pts[1][0], s.pts[1][1], s.pts[2][0], s.pts[2][1])
+		}
+	}
+	return b.String()
+}
+
+// plot walks the path in steps of at most a pixel and calls dot for every
+// pixel it crosses once. Dashed paths skip every other two pixels.
+func (p *Path) plot(dashed bool, dot func(x, y int)) {
+	var cx, cy float64
+	lastX, lastY := math.MinInt, math.MinInt
+	n := 0
+	visit := func(x, y float64) {
+		ix, iy := int(math.Floor(x)), int(math.Floor(y))
+		if ix == lastX && iy == lastY {
+			return
+		}
+		lastX, lastY = ix, iy
+		if !dashed || (n/2)%2 == 0 {
+			dot(ix, iy)
+		}
+		n++
+	}
+	for _, s := range p.segs {
+		switch s.op {
+		case PATH_MOVE:
+			cx, cy = s.pts[0][0], s.pts[0][1]
+			lastX, lastY = math.MinInt, math.MinInt
+			visit(cx, cy)
+		case PATH_LINE:
+			x, y := s.pts[0][0], s.pts[0][1]
+			steps := int(math.Ceil(math.Max(math.Abs(x-cx), math.Abs(y-cy))))
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / float64(steps)
+				visit(cx+t*(x-cx), cy+t*(y-cy))
+			}
+			cx, cy = x, y
+		case PATH_CURVE:
+			p1, p2, p3 := s.pts[0], s.pts[1], s.pts[2]
+			steps := 4 * int(math.Ceil(math.Abs(p3[0]-cx)+math.Abs(p3[1]-cy)+1))
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / float64(steps)
+				u := 1 - t
+				x := u*u*u*cx + 3*u*u*t*p1[0] + 3*u*t*t*p2[0] + t*t*t*p3[0]
+				y := u*u*u*cy + 3*u*u*t*p1[1] + 3*u*t*t*p2[1] + t*t*t*p3[1]
+				visit(x, y)
+			}
+			cx, cy = p3[0], p3[1]
+		}
+	}
+}
+
+// RasterCanvas draws into an RGBA image.
+type RasterCanvas struct {
+	img *image.RGBA
+}
+
+// NewRasterCanvas starts from a copy of the template image.
+func NewRasterCanvas(template image.Image) *RasterCanvas {
+	bounds := template.Bounds()
+	img := image.NewRGBA(bounds)
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			img.Set(x, y, template.At(x, y))
+		}
+	}
+	return &RasterCanvas{img: img}
+}
+
+func (c *RasterCanvas) Image() *image.RGBA {
+	return c.img
+}
+
+func (c *RasterCanvas) Bounds() image.Rectangle {
+	return c.img.Bounds()
+}
+
+func (c *RasterCanvas) SetPixel(x, y int, col color.Color) {
+	if !image.Pt(x, y).In(c.img.Bounds()) {
+		return
+	}
+	c.img.Set(x, y, col)
+}
+
+func (c *RasterCanvas) BlitSprite(img image.Image, xpos, ypos int) {
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			col := img.At(x, y)
+			if _, _, _, a := col.RGBA(); a == 0 {
+				continue
+			}
+			c.SetPixel(xpos+x-b.Min.X, ypos+y-b.Min.Y, col)
+		}
+	}
+}
+
+func (c *RasterCanvas) DrawPath(p *Path, col color.Color, dashed bool) {
+	p.plot(dashed, func(x, y int) {
+		c.SetPixel(x, y, col)
+	})
+}
+
+// ASCIICanvas draws like RasterCanvas and prints the result as one
+// character per pixel.
+type ASCIICanvas struct {
+	*RasterCanvas
+}
+
+func NewASCIICanvas(template image.Image) *ASCIICanvas {
+	return &ASCIICanvas{NewRasterCanvas(template)}
+}
+
+// IsRed tells the accent pixels of a tri-colour image from black, white
+// and grey.
+func IsRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0x8000 && g < 0x8000 && b < 0x8000
+}
+
+// asciiChar maps a pixel to a character: ink is '#', red is 'o', greys
+// are shades of '+' and ':', paper is blank.
+func asciiChar(col color.Color) byte {
+	if IsRed(col) {
+		return 'o'
+	}
+	switch y := color.GrayModel.Convert(col).(color.Gray).Y; {
+	case y < 0x40:
+		return '#'
+	case y < 0x80:
+		return '+'
+	case y < 0xC0:
+		return ':'
+	}
+	return ' '
+}
+
+func (c *ASCIICanvas) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	b := c.img.Bounds()
+	var n int64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		line := make([]byte, 0, b.Dx()+1)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			line = append(line, asciiChar(c.img.At(x, y)))
+		}
+		line = append([]byte(strings.TrimRight(string(line), " ")), '\n')
+		m, err := bw.Write(line)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
@@ -1,24 +1,59 @@
-package main
+package p_weather
 
 import (
 	"fmt"
 	"image"
 	"math"
-	"math/rand"
+	"strings"
 	"time"
 )
 
+const (
+	TEMPMODE_ACTUAL = iota
+	TEMPMODE_FEELSLIKE
+	TEMPMODE_BOTH
+)
+
+func ParseTempMode(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "", "actual":
+		return TEMPMODE_ACTUAL, nil
+	case "feelslike", "feels-like":
+		return TEMPMODE_FEELSLIKE, nil
+	case "both":
+		return TEMPMODE_BOTH, nil
+	}
+	return TEMPMODE_ACTUAL, fmt.Errorf("unknown temperature mode '%s'", name)
+}
+
 type DrawWeather struct {
 	XSTART              int
 	XSTEP               int
 	XFLAT               int
 	YSTEP               int
 	DEFAULT_DEGREE_PER_PIXEL float64
-
+	SNOW_MM_PER_PIXEL   float64
+	SNOW_MAX_PIXELS     int
+	SNOW_ROOF_MM        float64
+	SNOW_MELT_MM_PER_DEGREE float64
+	PRESSURE_TREND_HOURS int
+	PRESSURE_TREND_HPA  float64
+	DEWPOINT_MUGGY      float64
+	DEWPOINT_SPREAD     float64
+	HUMIDITY_DRY        int
+	TEMP_MODE           int
+	HEAT_WARNING        float64
+	FROST_WARNING       float64
+	STORM_BEAUFORT      int
+	SPREAD_MAX_PIXELS   int
+	VERBOSE             bool
+
+	drift    bool
 	img      image.Image
 	sprite   *Sprites
 	IMGEWIDTH, IMGHEIGHT int
 	tmin, tmax, temprange float64
+	lmin, lmax            float64
 	degreeperpixel        float64
 	ypos                  int
 }
@@ -30,6 +65,21 @@ func NewDrawWeather(canvas image.Image, sprites *Sprites) *DrawWeather {
 		XFLAT:               10,
 		YSTEP:               50,
 		DEFAULT_DEGREE_PER_PIXEL: 0.5,
+		SNOW_MM_PER_PIXEL:   1.0,
+		SNOW_MAX_PIXELS:     4,
+		SNOW_ROOF_MM:        2.0,
+		SNOW_MELT_MM_PER_DEGREE: 0.5,
+		PRESSURE_TREND_HOURS: 24,
+		PRESSURE_TREND_HPA:  3.0,
+		DEWPOINT_MUGGY:      16.0,
+		DEWPOINT_SPREAD:     2.0,
+		HUMIDITY_DRY:        25,
+		TEMP_MODE:           TEMPMODE_ACTUAL,
+		HEAT_WARNING:        30.0,
+		FROST_WARNING:       0.0,
+		STORM_BEAUFORT:      8,
+		SPREAD_MAX_PIXELS:   6,
+		VERBOSE:             true, // print the weather of every period drawn
 		img:      canvas,
 		sprite:   sprites,
 		IMGEWIDTH:  canvas.Bounds().Dx(),
@@ -38,19 +88,44 @@ func NewDrawWeather(canvas image.Image, sprites *Sprites) *DrawWeather {
 }
 
 func (dw *DrawWeather) mybezier(x, xa, ya, xb, yb float64) int {
-	xc := (xb + xa) / 2.0
 	d := xb - xa
 	t := (x - xa) / d
 	return int(dw.mybeizelfnc(t, ya, ya, yb, yb))
 }
 
 func (dw *DrawWeather) mybeizelfnc(t, d0, d1, d2, d3 float64) float64 {
-	return (1-t)*((1-t)*((1-t)*d0+t*d1) + t*((1-t)*d1+t*d2)) + t*((1-t)*((1-t)*d1+t*d2)+t*((1-t)*d2+t*d3)))
+	return (1-t)*((1-t)*((1-t)*d0+t*d1) + t*((1-t)*d1+t*d2)) + t*((1-t)*((1-t)*d1+t*d2)+t*((1-t)*d2+t*d3))
+}
+
+// bezierPath appends the same curve mybezier rasterises: x runs linearly
+// with t, so the control points sit at thirds of the segment.
+func (dw *DrawWeather) bezierPath(p *Path, xpos, n, ya, yb int) {
+	xa, xb := float64(xpos), float64(xpos+n)
+	y0, y1 := float64(ya), float64(yb)
+	p.CurveTo(xa+(xb-xa)/3, y0, xa+2*(xb-xa)/3, y1, xb, y1)
+	p.LineTo(xb+float64(dw.XFLAT), y1)
+}
+
+// warningPath traces the stretches of the temperature line marked in
+// wline, to be drawn over the curve in the accent colour.
+func (dw *DrawWeather) warningPath(tline []int, wline []bool) *Path {
+	p := &Path{}
+	for x := 0; x < dw.IMGEWIDTH; x++ {
+		if !wline[x] {
+			continue
+		}
+		if x == 0 || !wline[x-1] {
+			p.MoveTo(float64(x), float64(tline[x]))
+		} else {
+			p.LineTo(float64(x), float64(tline[x]))
+		}
+	}
+	return p
 }
 
 func (dw *DrawWeather) TimeDiffToPixels(dt time.Duration) int {
 	ds := dt.Seconds()
-	secondsPerPixel := float64(WeatherInfo.FORECAST_PERIOD_HOURS*60*60) / float64(dw.XSTEP)
+	secondsPerPixel := float64(FORECAST_PERIOD_HOURS*60*60) / float64(dw.XSTEP)
 	return int(ds / secondsPerPixel)
 }
 
@@ -60,38 +135,223 @@ func (dw *DrawWeather) DegToPix(t float64) int {
 	return y
 }
 
-func (dw *DrawWeather) Draw(ypos int, owm *OpenWeatherMap) {
+// accumulateSnow adds the snowfall of one forecast period to the snow
+// already on the ground and melts some of it away above zero.
+func (dw *DrawWeather) accumulateSnow(acc float64, f *WeatherInfo) float64 {
+	acc += f.Units.Millimetres(f.Snow)
+	if t := f.Units.Celsius(f.Temp); t > 0 {
+		acc -= dw.SNOW_MELT_MM_PER_DEGREE * t
+	}
+	if acc < 0 {
+		acc = 0
+	}
+	return acc
+}
+
+// SpreadToPix turns the disagreement of blended forecasts into how far
+// the temperature curve frays out on either side.
+func (dw *DrawWeather) SpreadToPix(spread float64) int {
+	n := int(spread / 2 / dw.degreeperpixel)
+	if n > dw.SPREAD_MAX_PIXELS {
+		n = dw.SPREAD_MAX_PIXELS
+	}
+	return n
+}
+
+func (dw *DrawWeather) SnowToPix(acc float64) int {
+	n := int(acc / dw.SNOW_MM_PER_PIXEL)
+	if n > dw.SNOW_MAX_PIXELS {
+		n = dw.SNOW_MAX_PIXELS
+	}
+	return n
+}
+
+func (dw *DrawWeather) SnowCapToPix(acc float64) int {
+	if acc < dw.SNOW_ROOF_MM {
+		return 0
+	}
+	return dw.SnowToPix(acc)
+}
+
+// drawPrecipitation draws rain, snow or a mixed pattern for one slot and
+// marks the ground under freezing rain in iline.
+func (dw *DrawWeather) drawPrecipitation(f *WeatherInfo, xpos, yClouds, width int, tline []int, iline []bool) {
+	rain, snow := f.Units.Millimetres(f.Rain), f.Units.Millimetres(f.Snow)
+	if f.IsMixed() {
+		dw.sprite.DrawSleet(rain+snow, f.Pop, xpos, yClouds, width, tline)
+	} else {
+		dw.sprite.DrawRain(rain, f.Pop, xpos, yClouds, width, tline)
+		dw.sprite.DrawSnow(snow, f.Pop, xpos, yClouds, width, tline)
+	}
+
+	if f.IsFreezingRain() {
+		dw.markLine(iline, xpos, width, true)
+	}
+}
+
+// curveTemp is the temperature the ground line follows.
+func (dw *DrawWeather) curveTemp(f *WeatherInfo) float64 {
+	if dw.TEMP_MODE == TEMPMODE_FEELSLIKE {
+		return f.FeelsLike
+	}
+	return f.Temp
+}
+
+// labelTemp is the temperature printed next to the house and at the
+// minimum and maximum of the curve.
+func (dw *DrawWeather) labelTemp(f *WeatherInfo) float64 {
+	if dw.TEMP_MODE == TEMPMODE_ACTUAL {
+		return f.Temp
+	}
+	return f.FeelsLike
+}
+
+// labelLine is the curve the temperature labels sit on: in BOTH mode
+// they show feels-like values and go with the dashed feels-like curve.
+func (dw *DrawWeather) labelLine(tline, fline []int) []int {
+	if dw.TEMP_MODE == TEMPMODE_BOTH {
+		return fline
+	}
+	return tline
+}
+
+// isWarning tells if a slot is hot, freezing or stormy enough to draw
+// its part of the curve in the accent colour.
+func (dw *DrawWeather) isWarning(f *WeatherInfo) bool {
+	t := f.Units.Celsius(f.Temp)
+	if t >= dw.HEAT_WARNING || t <= dw.FROST_WARNING {
+		return true
+	}
+	if f.ID >= 200 && f.ID < 300 {
+		return true
+	}
+	return Beaufort(f.Units.MetresPerSecond(math.Max(f.Windspeed, f.WindGust))) >= dw.STORM_BEAUFORT
+}
+
+func (dw *DrawWeather) markLine(line []bool, xpos, width int, value bool) {
+	for x := xpos; x < xpos+width && x < len(line); x++ {
+		line[x] = value
+	}
+}
+
+func (dw *DrawWeather) isDew(f *WeatherInfo) bool {
+	t := f.Units.Celsius(f.Temp)
+	return f.HasDewPoint && t > 0 && t-f.Units.Celsius(f.DewPoint) <= dw.DEWPOINT_SPREAD
+}
+
+// isHazy tells if the air is thick: the atmosphere codes from mist to
+// volcanic ash (7xx short of squalls and tornadoes) or a muggy dew point.
+func (dw *DrawWeather) isHazy(f *WeatherInfo) bool {
+	if f.ID >= 700 && f.ID < 770 {
+		return true
+	}
+	return f.HasDewPoint && f.Units.Celsius(f.DewPoint) >= dw.DEWPOINT_MUGGY
+}
+
+// drawHumidity hazes the air above the ground when it is hazy and cracks
+// the ground when the air is very dry.
+func (dw *DrawWeather) drawHumidity(f *WeatherInfo, xpos, width int, tline []int) {
+	if dw.isHazy(f) {
+		dw.sprite.DrawHaze(xpos, width, tline)
+	} else if f.Humidity > 0 && f.Humidity <= dw.HUMIDITY_DRY {
+		dw.sprite.DrawCracks(xpos, width, tline)
+	}
+}
+
+func (dw *DrawWeather) Draw(ypos int, ws *WeatherSeries) {
+	dw.DrawAt(ypos, ws, time.Now())
+}
+
+// currAt returns the current observation, or for a time well past it
+// the forecast that covers now.
+func (dw *DrawWeather) currAt(ws *WeatherSeries, now time.Time) *WeatherInfo {
+	f := ws.GetCurr()
+	if f == nil || now.Before(f.T.Add(time.Hour)) {
+		return f
+	}
+	if fc := ws.Get(now); fc != nil {
+		return fc
+	}
+	return f
+}
+
+// DrawAt draws the landscape as it looks at now, the house standing at
+// now and the forecast running from there.
+func (dw *DrawWeather) DrawAt(ypos int, ws *WeatherSeries, now time.Time) {
 	dw.ypos = ypos
 	nForecast := (dw.IMGEWIDTH - dw.XSTART) / dw.XSTEP
-	maxTime := time.Now().Add(time.Duration(WeatherInfo.FORECAST_PERIOD_HOURS*nForecast) * time.Hour)
-
-	dw.tmin, dw.tmax = owm.GetTempRange(maxTime)
+	maxTime := now.Add(time.Duration(FORECAST_PERIOD_HOURS*nForecast) * time.Hour)
+
+	dw.tmin, dw.tmax = ws.GetTempRange(maxTime)
+	dw.lmin, dw.lmax = dw.tmin, dw.tmax
+	if dw.TEMP_MODE != TEMPMODE_ACTUAL {
+		fmin, fmax := ws.GetFeelsLikeRange(maxTime)
+		dw.lmin, dw.lmax = fmin, fmax
+		if dw.TEMP_MODE == TEMPMODE_FEELSLIKE {
+			dw.tmin, dw.tmax = fmin, fmax
+		} else {
+			dw.tmin, dw.tmax = math.Min(dw.tmin, fmin), math.Max(dw.tmax, fmax)
+		}
+	}
 	dw.temprange = dw.tmax - dw.tmin
 
-	if dw.temprange < float64(dw.YSTEP) {
-		dw.degreeperpixel = dw.DEFAULT_DEGREE_PER_PIXEL
+	scale := ws.Units.DegreeScale()
+	if dw.temprange < float64(dw.YSTEP)*scale {
+		dw.degreeperpixel = dw.DEFAULT_DEGREE_PER_PIXEL * scale
 	} else {
 		dw.degreeperpixel = dw.temprange / float64(dw.YSTEP)
 	}
 
+	layout := NewLayout(image.Rect(0, 0, dw.IMGEWIDTH, dw.IMGHEIGHT))
+	dw.sprite.Layout = layout
+
 	tline := make([]int, dw.IMGEWIDTH+dw.XSTEP+1)
-	f := owm.GetCurr()
-	oldTemp := f.Temp
+	fline := make([]int, len(tline))
+	f := dw.currAt(ws, now)
+	oldTemp := dw.curveTemp(f)
 	oldY := dw.DegToPix(oldTemp)
+	oldFY := dw.DegToPix(f.FeelsLike)
+	pline := make([]int, len(tline))
+	oldP := dw.SpreadToPix(f.Spread)
 	for i := 0; i < dw.XSTART; i++ {
 		tline[i] = oldY
+		fline[i] = oldFY
+		pline[i] = oldP
 	}
+	tpath := &Path{}
+	fpath := &Path{}
+	tpath.MoveTo(0, float64(oldY))
+	tpath.LineTo(float64(dw.XSTART), float64(oldY))
+	fpath.MoveTo(0, float64(oldFY))
+	fpath.LineTo(float64(dw.XSTART), float64(oldFY))
 	yClouds := int(ypos - dw.YSTEP/2)
-	f.Print()
+	if dw.VERBOSE {
+		f.Print()
+	}
 
-	dw.sprite.Draw("house", 0, 0, oldY)
-	dw.sprite.DrawInt(oldTemp, 8, oldY+10)
+	sline := make([]int, len(tline))
+	snowAcc := dw.accumulateSnow(0, f)
+	for i := 0; i < dw.XSTART; i++ {
+		sline[i] = dw.SnowToPix(snowAcc)
+	}
+
+	dw.sprite.SnowCap = dw.SnowCapToPix(snowAcc)
+	dw.sprite.DrawRole("house", 0, 0, oldY)
+	dw.sprite.SnowCap = 0
+	trend := ws.GetForecastPressureTrend(dw.PRESSURE_TREND_HOURS)
+	chimney := dw.sprite.Anchor("house", "chimney")
+	dw.sprite.DrawSmoke(trend/dw.PRESSURE_TREND_HPA, chimney.X, oldY+chimney.Y)
+	lline := dw.labelLine(tline, fline)
+	layout.AddLabel(FormatNumber(dw.labelTemp(f), 0, true)+f.Units.TemperatureUnit(), 8, lline[0]+10, ALIGN_LEFT)
+	dw.sprite.Seed(f.T.Unix())
 	dw.sprite.DrawCloud(f.Clouds, 0, yClouds, dw.XSTART, dw.YSTEP/2)
-	dw.sprite.DrawRain(f.Rain, 0, yClouds, dw.XSTART, tline)
-	dw.sprite.DrawSnow(f.Snow, 0, yClouds, dw.XSTART, tline)
+	iline := make([]bool, len(tline))
+	dw.drawPrecipitation(f, 0, yClouds, dw.XSTART, tline, iline)
+	wline := make([]bool, len(tline))
+	dw.markLine(wline, 0, dw.XSTART, dw.isWarning(f))
 
-	t := time.Now()
-	dt := time.Duration(WeatherInfo.FORECAST_PERIOD_HOURS) * time.Hour
+	t := now
+	dt := time.Duration(FORECAST_PERIOD_HOURS) * time.Hour
 	tf := t
 
 	xpos := dw.XSTART
@@ -99,34 +359,46 @@ func (dw *DrawWeather) Draw(ypos int, owm *OpenWeatherMap) {
 
 	n := (dw.XSTEP - dw.XFLAT) / 2
 	for i := 0; i <= nForecast; i++ {
-		f = owm.Get(tf)
+		f = ws.Get(tf)
 		if f == nil {
 			continue
 		}
-		f.Print()
-		newTemp := f.Temp
+		if dw.VERBOSE {
+			f.Print()
+		}
+		newTemp := dw.curveTemp(f)
 		newY := dw.DegToPix(newTemp)
+		newFY := dw.DegToPix(f.FeelsLike)
+		newP := dw.SpreadToPix(f.Spread)
 		for j := 0; j < n; j++ {
 			tline[xpos+j] = dw.mybezier(float64(xpos+j), float64(xpos), float64(oldY), float64(xpos+n), float64(newY))
+			fline[xpos+j] = dw.mybezier(float64(xpos+j), float64(xpos), float64(oldFY), float64(xpos+n), float64(newFY))
+			pline[xpos+j] = oldP + (newP-oldP)*j/n
 		}
 
 		for j := 0; j < dw.XFLAT; j++ {
 			tline[xpos+j+n] = newY
+			fline[xpos+j+n] = newFY
+			pline[xpos+j+n] = newP
 		}
+		dw.bezierPath(tpath, xpos, n, oldY, newY)
+		dw.bezierPath(fpath, xpos, n, oldFY, newFY)
 
 		xpos += n + dw.XFLAT
 		n = (dw.XSTEP - dw.XFLAT)
 		oldTemp = newTemp
 		oldY = newY
+		oldFY = newFY
+		oldP = newP
 		tf = tf.Add(dt)
 	}
 
-	s := sun{Lat: owm.Lat, Lon: owm.Lon}
+	s := NewSun(ws.Latitude, ws.Longitude)
 	tf = t
 	xpos = dw.XSTART
 	objCounter := 0
 	for i := 0; i <= nForecast; i++ {
-		f = owm.Get(tf)
+		f = ws.Get(tf)
 		if f == nil {
 			continue
 		}
@@ -138,7 +410,7 @@ func (dw *DrawWeather) Draw(ypos int, owm *OpenWeatherMap) {
 
 		if tf.Before(tSunrise) && tf.Add(dt).After(tSunrise) {
 			dx := dw.TimeDiffToPixels(tSunrise.Sub(tf)) - dw.XSTEP/2
-			dw.sprite.Draw("sun", 0, xpos+dx, yMoon)
+			dw.sprite.DrawRole("sun", 0, xpos+dx, yMoon)
 			objCounter++
 			if objCounter == 2 {
 				break
@@ -147,7 +419,7 @@ func (dw *DrawWeather) Draw(ypos int, owm *OpenWeatherMap) {
 
 		if tf.Before(tSunset) && tf.Add(dt).After(tSunset) {
 			dx := dw.TimeDiffToPixels(tSunset.Sub(tf)) - dw.XSTEP/2
-			dw.sprite.Draw("moon", 0, xpos+dx, yMoon)
+			dw.sprite.DrawRole("moon", 0, xpos+dx, yMoon)
 			objCounter++
 			if objCounter == 2 {
 				break
@@ -164,71 +436,96 @@ func (dw *DrawWeather) Draw(ypos int, owm *OpenWeatherMap) {
 	xpos = dw.XSTART
 	n = (dw.XSTEP - dw.XFLAT) / 2
 	for i := 0; i <= nForecast; i++ {
-		f = owm.Get(tf)
+		f = ws.Get(tf)
 		if f == nil {
 			continue
 		}
 
 		yClouds := int(ypos - dw.YSTEP/2)
 
-		if f.Temp == dw.tmin && !isTminPrinted {
-			dw.sprite.DrawInt(f.Temp, xpos+n, tline[xpos+n]+10)
+		if dw.labelTemp(f) == dw.lmin && !isTminPrinted {
+			layout.AddLabel(FormatNumber(dw.labelTemp(f), 0, true), xpos+n, lline[xpos+n]+10, ALIGN_CENTER)
 			isTminPrinted = true
 		}
 
-		if f.Temp == dw.tmax && !isTmaxPrinted {
-			dw.sprite.DrawInt(f.Temp, xpos+n, tline[xpos+n]+10)
+		if dw.labelTemp(f) == dw.lmax && !isTmaxPrinted {
+			layout.AddLabel(FormatNumber(dw.labelTemp(f), 0, true), xpos+n, lline[xpos+n]+10, ALIGN_CENTER)
 			isTmaxPrinted = true
 		}
 
+		snowAcc = dw.accumulateSnow(snowAcc, f)
+		for j := 0; j < dw.XSTEP && xpos+j < len(sline); j++ {
+			sline[xpos+j] = dw.SnowToPix(snowAcc)
+		}
+		dw.sprite.SnowCap = dw.SnowCapToPix(snowAcc)
+
 		t0 := f.T.Add(-dt / 2)
 		t1 := f.T.Add(dt / 2)
 
 		dtOneHour := time.Duration(time.Hour)
-		dxOneHour := float64(dw.XSTEP) / float64(WeatherInfo.FORECAST_PERIOD_HOURS)
+		dxOneHour := float64(dw.XSTEP) / float64(FORECAST_PERIOD_HOURS)
 		tt := t0
 		xx := float64(xpos)
 		for tt.Before(t1) {
 			ix := int(xx)
 			if tt.Hour() == 12 {
-				dw.sprite.Draw("flower", 1, ix, tline[ix])
+				dw.sprite.DrawRole("flower.noon", 0, ix, tline[ix])
 			}
 			if tt.Hour() == 0 {
-				dw.sprite.Draw("flower", 0, ix, tline[ix])
+				dw.sprite.DrawRole("flower.midnight", 0, ix, tline[ix])
+			}
+			if (tt.Hour() == 12 || tt.Hour() == 0) && dw.isDew(f) {
+				dw.sprite.DrawDew(ix, tline[ix])
 			}
 			if tt.Hour() == 6 || tt.Hour() == 18 || tt.Hour() == 3 || tt.Hour() == 15 || tt.Hour() == 9 || tt.Hour() == 21 {
-				dw.sprite.DrawWind(f.WindSpeed, f.WindDeg, ix, tline)
+				speed, gust := f.Units.MetresPerSecond(f.Windspeed), f.Units.MetresPerSecond(f.WindGust)
+				dw.sprite.DrawWind(speed, gust, f.Winddeg, ix, tline)
+				dw.sprite.DrawGust(speed, gust, ix, tline)
 			}
 
 			tt = tt.Add(dtOneHour)
 			xx += dxOneHour
 		}
 
-		dw.sprite.DrawCloud(f.Clouds, xpos, yClouds, dw.XSTEP, dw.YSTEP/2)
-		dw.sprite.DrawRain(f.Rain, xpos, yClouds, dw.XSTEP, tline)
-		dw.sprite.DrawSnow(f.Snow, xpos, yClouds, dw.XSTEP, tline)
+		dw.sprite.SnowCap = 0
+
+		// in a time-lapse clouds keep their shape per forecast and drift
+		// left as now moves through the period, the precipitation with them
+		xsky := xpos
+		if dw.drift {
+			xsky = max(0, xpos+dw.TimeDiffToPixels(f.T.Sub(tf))-dw.XSTEP)
+		}
+		dw.sprite.Seed(f.T.Unix())
+		dw.sprite.DrawCloud(f.Clouds, xsky, yClouds, dw.XSTEP, dw.YSTEP/2)
+		dw.drawPrecipitation(f, xsky, yClouds, dw.XSTEP, tline, iline)
+		dw.drawHumidity(f, xpos, dw.XSTEP, tline)
+		dw.markLine(wline, xpos, dw.XSTEP, dw.isWarning(f))
 
 		xpos += dw.XSTEP
 		tf = tf.Add(dt)
 	}
 
-	black := 0
+	dw.sprite.DrawSnowCover(sline, tline, 0, dw.IMGEWIDTH)
+	dw.sprite.DrawIce(iline, tline, 0, dw.IMGEWIDTH)
+
+	dw.sprite.DrawFuzz(pline, tline, 0, dw.IMGEWIDTH)
+	if dw.TEMP_MODE == TEMPMODE_BOTH {
+		dw.sprite.DrawPath(fpath, dw.sprite.Black, true)
+	}
+	dw.sprite.DrawPath(tpath, dw.sprite.Black, false)
+	dw.sprite.DrawPath(dw.warningPath(tline, wline), dw.sprite.Accent(), false)
+
 	for x := 0; x < dw.IMGEWIDTH; x++ {
 		if tline[x] < dw.IMGHEIGHT {
-			dw.sprite.Dot(x, tline[x], black)
+			layout.AddBox(image.Rect(x, tline[x], x+1, tline[x]+1))
 		} else {
 			fmt.Printf("out of range: %d - %d(max %d)\n", x, tline[x], dw.IMGHEIGHT)
 		}
+		if dw.TEMP_MODE == TEMPMODE_BOTH && fline[x] < dw.IMGHEIGHT {
+			layout.AddBox(image.Rect(x, fline[x], x+1, fline[x]+1))
+		}
 	}
-}
-
-func main() {
-	// Assuming you have methods to create your canvas and sprites
-	canvas := createCanvas() // Implement your canvas creation logic
-	sprites := loadSprites()  // Implement your sprite loading logic
-
-	dw := NewDrawWeather(canvas, sprites)
-	owm := NewOpenWeatherMap() // Implement your OpenWeatherMap initialization logic
 
-	dw.Draw(100, owm) // Replace 100 with your desired ypos
+	dw.sprite.Layout = nil
+	layout.Draw(dw.sprite)
 }
@@ -0,0 +1,208 @@
+package p_weather
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func newTestDrawWeather() *DrawWeather {
+	return NewDrawWeather(image.NewRGBA(image.Rect(0, 0, 296, 128)), nil)
+}
+
+func TestAccumulateSnow(t *testing.T) {
+	tests := []struct {
+		name  string
+		acc   float64
+		snow  float64
+		temp  float64
+		units Units
+		want  float64
+	}{
+		{"fresh snow below zero", 0, 2, -3, UNITS_METRIC, 2},
+		{"adds up", 1.5, 2, -1, UNITS_METRIC, 3.5},
+		{"melts above zero", 3, 0, 2, UNITS_METRIC, 2},
+		{"melts away completely", 1, 0, 10, UNITS_METRIC, 0},
+		{"zero degrees keeps it", 2, 0, 0, UNITS_METRIC, 2},
+		{"fahrenheit below freezing", 0, 1, 23, UNITS_IMPERIAL, 25.4},
+		{"fahrenheit above freezing", 2, 0, 35.6, UNITS_IMPERIAL, 1},
+		{"kelvin above freezing", 2, 0, KTOC + 2, UNITS_SI, 1},
+	}
+	dw := newTestDrawWeather()
+	for _, tt := range tests {
+		f := &WeatherInfo{Snow: tt.snow, Temp: tt.temp, Units: tt.units}
+		if got := dw.accumulateSnow(tt.acc, f); !near(got, tt.want) {
+			t.Errorf("%s: accumulateSnow(%v) = %v, want %v", tt.name, tt.acc, got, tt.want)
+		}
+	}
+}
+
+func TestSnowToPix(t *testing.T) {
+	tests := []struct {
+		acc       float64
+		pix, roof int
+	}{
+		{0, 0, 0},
+		{0.5, 0, 0},
+		{1.5, 1, 0},
+		{2, 2, 2},
+		{3.9, 3, 3},
+		{50, 4, 4},
+	}
+	dw := newTestDrawWeather()
+	for _, tt := range tests {
+		if got := dw.SnowToPix(tt.acc); got != tt.pix {
+			t.Errorf("SnowToPix(%v) = %d, want %d", tt.acc, got, tt.pix)
+		}
+		if got := dw.SnowCapToPix(tt.acc); got != tt.roof {
+			t.Errorf("SnowCapToPix(%v) = %d, want %d", tt.acc, got, tt.roof)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+func TestParseTempMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    int
+		wantErr bool
+	}{
+		{"", TEMPMODE_ACTUAL, false},
+		{"actual", TEMPMODE_ACTUAL, false},
+		{"feelslike", TEMPMODE_FEELSLIKE, false},
+		{"feels-like", TEMPMODE_FEELSLIKE, false},
+		{"Both", TEMPMODE_BOTH, false},
+		{"windchill", TEMPMODE_ACTUAL, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTempMode(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTempMode(%q) = %d, %v, want %d, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLabelLine(t *testing.T) {
+	tline, fline := []int{10, 11}, []int{20, 21}
+	for mode, want := range map[int]int{TEMPMODE_ACTUAL: 10, TEMPMODE_FEELSLIKE: 10, TEMPMODE_BOTH: 20} {
+		dw := newTestDrawWeather()
+		dw.TEMP_MODE = mode
+		if got := dw.labelLine(tline, fline)[0]; got != want {
+			t.Errorf("mode %d: label line starts at %d, want %d", mode, got, want)
+		}
+	}
+}
+
+func TestBezierPath(t *testing.T) {
+	tests := []struct {
+		xpos, n, ya, yb int
+		want            string
+	}{
+		{10, 30, 50, 20, "M10.0 50.0C20.0 50.0 30.0 20.0 40.0 20.0L50.0 20.0"},
+		{0, 15, 40, 60, "M0.0 40.0C5.0 40.0 10.0 60.0 15.0 60.0L25.0 60.0"},
+		{100, 30, 30, 30, "M100.0 30.0C110.0 30.0 120.0 30.0 130.0 30.0L140.0 30.0"},
+	}
+	for _, tt := range tests {
+		dw := newTestDrawWeather()
+		p := &Path{}
+		p.MoveTo(float64(tt.xpos), float64(tt.ya))
+		dw.bezierPath(p, tt.xpos, tt.n, tt.ya, tt.yb)
+		if got := p.String(); got != tt.want {
+			t.Errorf("bezierPath(%d, %d, %d, %d) = %q, want %q", tt.xpos, tt.n, tt.ya, tt.yb, got, tt.want)
+		}
+
+		// the vector curve must follow the rasterised one
+		plotted := map[int][]int{}
+		p.plot(false, func(x, y int) { plotted[x] = append(plotted[x], y) })
+		for x := tt.xpos; x < tt.xpos+tt.n; x++ {
+			want := dw.mybezier(float64(x), float64(tt.xpos), float64(tt.ya), float64(tt.xpos+tt.n), float64(tt.yb))
+			ok := false
+			for _, y := range plotted[x] {
+				if y-want <= 1 && want-y <= 1 {
+					ok = true
+				}
+			}
+			if !ok {
+				t.Errorf("bezierPath(%d, %d, %d, %d): x=%d plotted at %v, mybezier gives %d", tt.xpos, tt.n, tt.ya, tt.yb, x, plotted[x], want)
+			}
+		}
+	}
+}
+
+func TestIsHazy(t *testing.T) {
+	dw := newTestDrawWeather()
+	tests := []struct {
+		name     string
+		id       int
+		humidity int
+		dew      float64 // °C
+		want     bool
+	}{
+		{"mist", 701, 0, 0, true},
+		{"smoke", 711, 0, 0, true},
+		{"haze", 721, 0, 0, true},
+		{"dust whirls", 731, 0, 0, true},
+		{"fog", 741, 0, 0, true},
+		{"sand", 751, 0, 0, true},
+		{"dust", 761, 0, 0, true},
+		{"volcanic ash", 762, 0, 0, true},
+		{"squalls", 771, 0, 0, false},
+		{"tornado", 781, 0, 0, false},
+		{"clear and muggy", 800, 80, 18, true},
+		{"clear and fresh", 800, 50, 5, false},
+		{"rain", 500, 90, 12, false},
+		{"no humidity reading", 800, 0, 20, false},
+	}
+	for _, tt := range tests {
+		f := &WeatherInfo{ID: tt.id, Humidity: tt.humidity, DewPoint: tt.dew, HasDewPoint: tt.humidity > 0}
+		if got := dw.isHazy(f); got != tt.want {
+			t.Errorf("%s: isHazy() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCloudDrift(t *testing.T) {
+	// now is half an hour before the first forecast, so drifting clouds
+	// of the first slot move most of a slot to the left, past XSTART
+	now := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+	series := func(clouds int, rain float64) *WeatherSeries {
+		ws := &WeatherSeries{Latitude: 52.2, Longitude: 21.0, F: []*WeatherInfo{{T: now, ID: 800, Temp: 5, Pressure: 1013}}}
+		for h := 0; h <= 24; h += FORECAST_PERIOD_HOURS {
+			ws.F = append(ws.F, &WeatherInfo{T: now.Add(time.Duration(h)*time.Hour + 30*time.Minute), ID: 800,
+				Clouds: clouds, Rain: rain, Pop: 1, Temp: 5, Pressure: 1013})
+		}
+		return ws
+	}
+	render := func(ws *WeatherSeries, drift bool) *image.RGBA {
+		spr := newTestSprites()
+		dw := NewDrawWeather(image.NewRGBA(image.Rect(0, 0, 296, 128)), spr)
+		dw.VERBOSE, dw.drift = false, drift
+		dw.DrawAt(65, ws, now)
+		return spr.Canvas.(*RasterCanvas).Image()
+	}
+	// leftOfSlots tells if two renders differ left of the first slot
+	leftOfSlots := func(a, b *image.RGBA) bool {
+		for y := 0; y < 128; y++ {
+			for x := 0; x < newTestDrawWeather().XSTART; x++ {
+				if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, drift := range []bool{false, true} {
+		clear, cloudy, rainy := render(series(0, 0), drift), render(series(100, 0), drift), render(series(100, 5), drift)
+		if got := leftOfSlots(clear, cloudy); got != drift {
+			t.Errorf("drift %v: clouds left of the first slot %v", drift, got)
+		}
+		if got := leftOfSlots(cloudy, rainy); got != drift {
+			t.Errorf("drift %v: rain left of the first slot %v", drift, got)
+		}
+	}
+}
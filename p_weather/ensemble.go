@@ -0,0 +1,154 @@
+package p_weather
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const PROVIDER_ENSEMBLE = "ensemble"
+
+// Ensemble blends the series of several providers slot by slot, so one
+// provider's outlier cannot take over the display. The slots follow the
+// first provider that returns data; all of them must use the same units.
+type Ensemble struct {
+	Providers []Provider
+}
+
+func NewEnsemble(providers ...Provider) *Ensemble {
+	return &Ensemble{Providers: providers}
+}
+
+func (e *Ensemble) Name() string {
+	return PROVIDER_ENSEMBLE
+}
+
+func (e *Ensemble) Fetch() (*WeatherSeries, error) {
+	members := []*WeatherSeries{}
+	for _, p := range e.Providers {
+		ws, err := p.Fetch()
+		if err != nil {
+			fmt.Printf("Ensemble: %s: %s\n", p.Name(), err)
+			continue
+		}
+		if len(ws.F) == 0 {
+			continue
+		}
+		if len(members) > 0 && ws.Units != members[0].Units {
+			fmt.Printf("Ensemble: %s: units %s, expected %s\n", p.Name(), ws.Units, members[0].Units)
+			continue
+		}
+		members = append(members, ws)
+	}
+	if len(members) == 0 {
+		return nil, errors.New("no provider returned data")
+	}
+
+	ref := members[0]
+	out := &WeatherSeries{Latitude: ref.Latitude, Longitude: ref.Longitude, Units: ref.Units}
+	curr := []*WeatherInfo{}
+	for _, m := range members {
+		curr = append(curr, m.F[0])
+	}
+	out.F = append(out.F, blendWeather(ref.F[0].T, curr))
+
+	period := time.Duration(FORECAST_PERIOD_HOURS) * time.Hour
+	for _, f := range ref.F[1:] {
+		slot := []*WeatherInfo{}
+		for _, m := range members {
+			if len(m.F) < 2 {
+				continue
+			}
+			if fc := nearestForecast(m.F[1:], f.T); fc != nil && absDuration(fc.T.Sub(f.T)) <= period/2 {
+				slot = append(slot, fc)
+			}
+		}
+		out.F = append(out.F, blendWeather(f.T, slot))
+	}
+	return out, nil
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	v := append([]float64{}, values...)
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
+
+// blendWeather takes the median of temperatures, dew points, humidity,
+// clouds and amounts, the highest probability and gust, and averages the wind as
+// vectors. Spread is the range of the temperatures.
+func blendWeather(t time.Time, infos []*WeatherInfo) *WeatherInfo {
+	field := func(value func(*WeatherInfo) float64, skipZero bool) []float64 {
+		v := []float64{}
+		for _, f := range infos {
+			if x := value(f); x != 0 || !skipZero {
+				v = append(v, x)
+			}
+		}
+		return v
+	}
+
+	temps := field(func(f *WeatherInfo) float64 { return f.Temp }, false)
+	b := &WeatherInfo{
+		T:         t,
+		Units:     infos[0].Units,
+		Temp:      median(temps),
+		FeelsLike: median(field(func(f *WeatherInfo) float64 { return f.FeelsLike }, false)),
+		Pressure:  median(field(func(f *WeatherInfo) float64 { return f.Pressure }, true)),
+		Humidity:  int(median(field(func(f *WeatherInfo) float64 { return float64(f.Humidity) }, true))),
+		Clouds:    int(median(field(func(f *WeatherInfo) float64 { return float64(f.Clouds) }, false))),
+		Rain:      median(field(func(f *WeatherInfo) float64 { return f.Rain }, false)),
+		Snow:      median(field(func(f *WeatherInfo) float64 { return f.Snow }, false)),
+	}
+
+	// a provider without humidity has no dew point to contribute
+	b.DewPoint = b.Units.FromCelsius(dewPoint(0, 0))
+	dews := []float64{}
+	for _, f := range infos {
+		if f.HasDewPoint {
+			dews = append(dews, f.DewPoint)
+		}
+	}
+	if len(dews) > 0 {
+		b.DewPoint, b.HasDewPoint = median(dews), true
+	}
+
+	var u, v float64
+	wet := 0
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, f := range infos {
+		rad := f.Winddeg * math.Pi / 180
+		u += f.Windspeed * math.Sin(rad)
+		v += f.Windspeed * math.Cos(rad)
+		b.Pop = math.Max(b.Pop, f.Pop)
+		b.WindGust = math.Max(b.WindGust, f.WindGust)
+		if isPrecipitating(f) {
+			wet++
+		}
+		lo, hi = math.Min(lo, f.Temp), math.Max(hi, f.Temp)
+	}
+	n := float64(len(infos))
+	b.Windspeed = math.Hypot(u/n, v/n)
+	b.Winddeg = math.Mod(math.Atan2(u, v)*180/math.Pi+360, 360)
+	b.Spread = hi - lo
+
+	// the condition comes from the first provider agreeing with the
+	// majority on whether it precipitates
+	b.ID = infos[0].ID
+	for _, f := range infos {
+		if isPrecipitating(f) == (2*wet > len(infos)) {
+			b.ID = f.ID
+			break
+		}
+	}
+	return b
+}
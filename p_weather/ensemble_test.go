@@ -0,0 +1,53 @@
+package p_weather
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBlendWeather(t *testing.T) {
+	noDew := dewPoint(0, 0)
+	tests := []struct {
+		name  string
+		infos []*WeatherInfo
+		check func(b *WeatherInfo) bool
+	}{
+		{"median temperature", []*WeatherInfo{{Temp: 10}, {Temp: 12}, {Temp: 20}},
+			func(b *WeatherInfo) bool { return b.Temp == 12 && b.Spread == 10 }},
+		{"median of two", []*WeatherInfo{{Temp: 10}, {Temp: 13}},
+			func(b *WeatherInfo) bool { return b.Temp == 11.5 && b.Spread == 3 }},
+		{"dew point of those that have one", []*WeatherInfo{{DewPoint: noDew}, {DewPoint: 8, HasDewPoint: true}, {DewPoint: 10, HasDewPoint: true}},
+			func(b *WeatherInfo) bool { return b.DewPoint == 9 && b.HasDewPoint }},
+		{"no dew point at all", []*WeatherInfo{{DewPoint: noDew}, {DewPoint: noDew}},
+			func(b *WeatherInfo) bool { return !b.HasDewPoint }},
+		{"dew point of 0°C is a dew point", []*WeatherInfo{{DewPoint: 0, HasDewPoint: true}, {DewPoint: 4}},
+			func(b *WeatherInfo) bool { return b.DewPoint == 0 && b.HasDewPoint }},
+		{"pressure and humidity skip missing values", []*WeatherInfo{{Pressure: 0, Humidity: 0}, {Pressure: 1010, Humidity: 40}, {Pressure: 1020, Humidity: 60}},
+			func(b *WeatherInfo) bool { return b.Pressure == 1015 && b.Humidity == 50 }},
+		{"highest probability and gust", []*WeatherInfo{{Pop: 0.2, WindGust: 12}, {Pop: 0.9, WindGust: 8}},
+			func(b *WeatherInfo) bool { return b.Pop == 0.9 && b.WindGust == 12 }},
+		{"wind averaged as vectors", []*WeatherInfo{{Windspeed: 5, Winddeg: 350}, {Windspeed: 5, Winddeg: 10}},
+			func(b *WeatherInfo) bool {
+				return math.Abs(b.Windspeed-4.92) < 0.01 && (b.Winddeg < 0.01 || b.Winddeg > 359.99)
+			}},
+		{"opposite winds cancel", []*WeatherInfo{{Windspeed: 5, Winddeg: 90}, {Windspeed: 5, Winddeg: 270}},
+			func(b *WeatherInfo) bool { return b.Windspeed < 0.01 }},
+		{"condition of the wet majority", []*WeatherInfo{{ID: 800}, {ID: 500}, {ID: 501}},
+			func(b *WeatherInfo) bool { return b.ID == 500 }},
+		{"condition of the dry majority", []*WeatherInfo{{ID: 500}, {ID: 803}, {ID: 800}},
+			func(b *WeatherInfo) bool { return b.ID == 803 }},
+	}
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		for _, f := range tt.infos {
+			if f.ID == 0 {
+				f.ID = 800
+			}
+		}
+		b := blendWeather(at, tt.infos)
+		if !b.T.Equal(at) || !tt.check(b) {
+			t.Errorf("%s: blended %+v", tt.name, b)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package p_weather
+
+import (
+	"time"
+)
+
+// WeatherSeries is what the renderer draws: the current observation
+// followed by the forecast, in ascending time.
+type WeatherSeries struct {
+	Latitude  float64
+	Longitude float64
+	Units     Units
+	F         []*WeatherInfo
+}
+
+// Provider is a configured source of weather data.
+type Provider interface {
+	Name() string
+	Fetch() (*WeatherSeries, error)
+}
+
+func (ws *WeatherSeries) GetCurr() *WeatherInfo {
+	if len(ws.F) == 0 {
+		return nil
+	}
+	return ws.F[0]
+}
+
+func (ws *WeatherSeries) Get(time time.Time) *WeatherInfo {
+	for _, f := range ws.F {
+		if f.T.After(time) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (ws *WeatherSeries) getRange(maxtime time.Time, value func(*WeatherInfo) float64) (float64, float64) {
+	tmax := -999.0
+	tmin := 999.0
+	for i, f := range ws.F {
+		if i == 0 {
+			continue
+		}
+		if f.T.After(maxtime) {
+			break
+		}
+		v := value(f)
+		if v > tmax {
+			tmax = v
+		}
+		if v < tmin {
+			tmin = v
+		}
+	}
+	return tmin, tmax
+}
+
+func (ws *WeatherSeries) GetTempRange(maxtime time.Time) (float64, float64) {
+	return ws.getRange(maxtime, func(f *WeatherInfo) float64 { return f.Temp })
+}
+
+func (ws *WeatherSeries) GetFeelsLikeRange(maxtime time.Time) (float64, float64) {
+	return ws.getRange(maxtime, func(f *WeatherInfo) float64 { return f.FeelsLike })
+}
+
+// GetPrecipitation adds up the rain and snow forecast until maxtime.
+func (ws *WeatherSeries) GetPrecipitation(maxtime time.Time) float64 {
+	total := 0.0
+	for i, f := range ws.F {
+		if i == 0 {
+			continue
+		}
+		if f.T.After(maxtime) {
+			break
+		}
+		total += f.Rain + f.Snow
+	}
+	return total
+}
+
+// GetForecastPressureTrend returns the pressure change in hPa the
+// forecast expects between the current observation and the given number
+// of hours later. Unlike a barometer's tendency it looks ahead, not at
+// the past hours.
+func (ws *WeatherSeries) GetForecastPressureTrend(hours int) float64 {
+	curr := ws.GetCurr()
+	if curr == nil || curr.Pressure == 0 {
+		return 0
+	}
+	f := ws.Get(curr.T.Add(time.Duration(hours) * time.Hour))
+	if f == nil {
+		f = ws.F[len(ws.F)-1]
+	}
+	if f.Pressure == 0 {
+		return 0
+	}
+	return f.Pressure - curr.Pressure
+}
@@ -0,0 +1,39 @@
+package p_weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetForecastPressureTrend(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	// series builds a 3-hourly series from now with the given pressures
+	series := func(pressures ...float64) *WeatherSeries {
+		ws := &WeatherSeries{}
+		for i, p := range pressures {
+			ws.F = append(ws.F, &WeatherInfo{T: now.Add(time.Duration(i) * 3 * time.Hour), Pressure: p})
+		}
+		return ws
+	}
+	tests := []struct {
+		name  string
+		ws    *WeatherSeries
+		hours int
+		want  float64
+	}{
+		{"rising", series(1000, 1002, 1004, 1006), 6, 6},
+		{"falling", series(1020, 1015, 1010, 1005), 6, -15},
+		{"steady", series(1013, 1014, 1012, 1013), 6, 0},
+		{"first entry after the span", series(1000, 1001, 1002, 1003, 1004), 4, 2},
+		{"forecast shorter than the span", series(1010, 1008, 1006), 24, -4},
+		{"no current pressure", series(0, 1010, 1020), 3, 0},
+		{"no forecast pressure", series(1010, 0, 0), 3, 0},
+		{"no forecast", series(1010), 24, 0},
+		{"empty", series(), 24, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.ws.GetForecastPressureTrend(tt.hours); got != tt.want {
+			t.Errorf("%s: GetForecastPressureTrend(%d) = %v, want %v", tt.name, tt.hours, got, tt.want)
+		}
+	}
+}
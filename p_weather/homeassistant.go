@@ -0,0 +1,315 @@
+package p_weather
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const PROVIDER_HA = "homeassistant"
+
+// HA_CONDITIONS maps Home Assistant weather conditions to the
+// OpenWeatherMap condition codes the renderer understands.
+var HA_CONDITIONS = map[string]int{
+	"clear-night":     800,
+	"sunny":           800,
+	"windy":           800,
+	"partlycloudy":    802,
+	"windy-variant":   803,
+	"cloudy":          804,
+	"fog":             741,
+	"rainy":           500,
+	"pouring":         502,
+	"snowy":           601,
+	"snowy-rainy":     616,
+	"hail":            611,
+	"lightning":       210,
+	"lightning-rainy": 201,
+	"exceptional":     781,
+}
+
+// HA_CLOUDS stands in for the cloud coverage when the entity has none.
+var HA_CLOUDS = map[string]int{
+	"clear-night": 0, "sunny": 0, "windy": 0, "partlycloudy": 50, "windy-variant": 70,
+}
+
+// HomeAssistant reads the current conditions and the forecast of a
+// weather entity through the Home Assistant REST API, authenticated with
+// a long-lived access token.
+type HomeAssistant struct {
+	WeatherSeries
+	URL           string
+	Token         string
+	Entity        string
+	FORECAST_TYPE string
+	client        *http.Client
+}
+
+func NewHomeAssistant(url, token, entity string, units Units) *HomeAssistant {
+	return &HomeAssistant{
+		WeatherSeries: WeatherSeries{Units: units},
+		URL:           strings.TrimSuffix(url, "/"),
+		Token:         token,
+		Entity:        entity,
+		FORECAST_TYPE: "hourly", // hourly, twice_daily or daily
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (ha *HomeAssistant) Name() string {
+	return PROVIDER_HA
+}
+
+func (ha *HomeAssistant) call(method, path string, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, ha.URL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+ha.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ha.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type haState struct {
+	State       string                 `json:"state"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	LastUpdated time.Time              `json:"last_updated"`
+}
+
+func (ha *HomeAssistant) Fetch() (*WeatherSeries, error) {
+	if ha.Latitude == 0 && ha.Longitude == 0 {
+		var config struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		}
+		if err := ha.call(http.MethodGet, "/api/config", nil, &config); err != nil {
+			return nil, err
+		}
+		ha.Latitude, ha.Longitude = config.Latitude, config.Longitude
+	}
+
+	var state haState
+	if err := ha.call(http.MethodGet, "/api/states/"+ha.Entity, nil, &state); err != nil {
+		return nil, err
+	}
+	if state.State == "unavailable" || state.State == "unknown" {
+		return nil, fmt.Errorf("%s is %s", ha.Entity, state.State)
+	}
+
+	// older Home Assistant versions carry the forecast as an attribute,
+	// newer ones only answer the get_forecasts service
+	forecast, ok := state.Attributes["forecast"].([]interface{})
+	if !ok {
+		var resp struct {
+			ServiceResponse map[string]struct {
+				Forecast []interface{} `json:"forecast"`
+			} `json:"service_response"`
+		}
+		body := map[string]string{"entity_id": ha.Entity, "type": ha.FORECAST_TYPE}
+		if err := ha.call(http.MethodPost, "/api/services/weather/get_forecasts?return_response", body, &resp); err != nil {
+			return nil, err
+		}
+		forecast = resp.ServiceResponse[ha.Entity].Forecast
+	}
+
+	t := state.LastUpdated
+	if t.IsZero() {
+		t = time.Now()
+	}
+	curr := ha.weatherInfo(t, state.State, state.Attributes, state.Attributes)
+	if curr == nil {
+		return nil, fmt.Errorf("%s has no temperature", ha.Entity)
+	}
+	F := []*WeatherInfo{curr}
+	entries := []*WeatherInfo{}
+	for _, e := range forecast {
+		fdata, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ft, err := time.Parse(time.RFC3339, fmt.Sprint(fdata["datetime"]))
+		if err != nil {
+			continue
+		}
+		cond, _ := fdata["condition"].(string)
+		if f := ha.weatherInfo(ft, cond, fdata, state.Attributes); f != nil {
+			entries = append(entries, f)
+		}
+	}
+	ha.F = append(F, resample(entries)...)
+	return &ha.WeatherSeries, nil
+}
+
+func haNumber(data map[string]interface{}, key string) (float64, bool) {
+	v, ok := data[key].(float64)
+	return v, ok
+}
+
+// haToMetric converts a value in the unit Home Assistant reports to °C,
+// m/s, hPa or mm.
+func haToMetric(v float64, unit string) float64 {
+	switch unit {
+	case "°F":
+		return (v - 32) * 5 / 9
+	case "K":
+		return v - KTOC
+	case "km/h":
+		return v / 3.6
+	case "mph":
+		return v * 0.44704
+	case "kn":
+		return v * 0.514444
+	case "ft/s":
+		return v * 0.3048
+	case "inHg":
+		return v * 33.8639
+	case "mmHg":
+		return v * 1.33322
+	case "kPa":
+		return v * 10
+	case "psi":
+		return v * 68.9476
+	case "in":
+		return v * 25.4
+	}
+	return v // °C, m/s, hPa, mbar, mm
+}
+
+// weatherInfo maps a state or forecast entry, taking the units from the
+// entity's attributes. Entries without a temperature give nil.
+func (ha *HomeAssistant) weatherInfo(t time.Time, condition string, data, attrs map[string]interface{}) *WeatherInfo {
+	unit := func(key, def string) string {
+		if u, ok := attrs[key].(string); ok {
+			return u
+		}
+		return def
+	}
+	tempUnit := unit("temperature_unit", "°C")
+	windUnit := unit("wind_speed_unit", "km/h")
+
+	id, ok := HA_CONDITIONS[condition]
+	if !ok {
+		id = 804 // overcast, like the cloud coverage below
+	}
+	clouds, ok := HA_CLOUDS[condition]
+	if !ok {
+		clouds = 100
+	}
+	if v, ok := haNumber(data, "cloud_coverage"); ok {
+		clouds = int(v)
+	}
+
+	temp, ok := haNumber(data, "temperature")
+	if !ok {
+		return nil
+	}
+	temp = haToMetric(temp, tempUnit)
+	humidity := 0
+	dew := dewPoint(0, 0) // unknown without humidity
+	if v, ok := haNumber(data, "humidity"); ok {
+		humidity = int(v)
+		dew = dewPoint(temp, humidity)
+		if v, ok := haNumber(data, "dew_point"); ok {
+			dew = haToMetric(v, tempUnit)
+		}
+	}
+	pressure := 0.0
+	if v, ok := haNumber(data, "pressure"); ok {
+		pressure = haToMetric(v, unit("pressure_unit", "hPa"))
+	}
+	windspeed := 0.0
+	if v, ok := haNumber(data, "wind_speed"); ok {
+		windspeed = haToMetric(v, windUnit)
+	}
+	windgust := windspeed
+	if v, ok := haNumber(data, "wind_gust_speed"); ok {
+		windgust = haToMetric(v, windUnit)
+	}
+	winddeg, _ := haNumber(data, "wind_bearing")
+
+	var rain, snow float64
+	if v, ok := haNumber(data, "precipitation"); ok {
+		v = haToMetric(v, unit("precipitation_unit", "mm"))
+		switch condition {
+		case "snowy":
+			snow = v
+		case "snowy-rainy", "hail":
+			rain, snow = v/2, v/2
+		default:
+			rain = v
+		}
+	}
+	// without a probability, as in the current state, what falls is certain
+	pop := 0.0
+	if v, ok := haNumber(data, "precipitation_probability"); ok {
+		pop = v / 100
+	} else if rain+snow > 0 {
+		pop = 1
+	}
+
+	feelslike := apparentTemp(temp, windspeed, humidity)
+	if v, ok := haNumber(data, "apparent_temperature"); ok {
+		feelslike = haToMetric(v, tempUnit)
+	}
+
+	u := ha.Units
+	return &WeatherInfo{T: t, ID: id, Clouds: clouds, Rain: u.FromMillimetres(rain), Snow: u.FromMillimetres(snow), Pop: pop,
+		Windspeed: u.FromMetresPerSecond(windspeed), WindGust: u.FromMetresPerSecond(windgust), Winddeg: winddeg,
+		Temp: u.FromCelsius(temp), Pressure: pressure, Humidity: humidity,
+		DewPoint: u.FromCelsius(dew), HasDewPoint: humidity > 0, FeelsLike: u.FromCelsius(feelslike), Units: u}
+}
+
+// resample merges forecast entries shorter than a forecast period into
+// periods, adding up precipitation, the way OpenWeatherMap reports it.
+// Each period keeps the conditions of its middle entry.
+func resample(entries []*WeatherInfo) []*WeatherInfo {
+	if len(entries) < 2 {
+		return entries
+	}
+	period := time.Duration(FORECAST_PERIOD_HOURS) * time.Hour
+	step := entries[1].T.Sub(entries[0].T)
+	if step <= 0 || step >= period {
+		return entries
+	}
+	n := int(period / step)
+
+	out := []*WeatherInfo{}
+	for i := 0; i < len(entries); i += n {
+		group := entries[i:]
+		if len(group) > n {
+			group = group[:n]
+		}
+		f := *group[len(group)/2]
+		f.Rain, f.Snow, f.Pop, f.WindGust = 0, 0, 0, 0
+		for _, g := range group {
+			f.Rain += g.Rain
+			f.Snow += g.Snow
+			if g.Pop > f.Pop {
+				f.Pop = g.Pop
+			}
+			if g.WindGust > f.WindGust {
+				f.WindGust = g.WindGust
+			}
+		}
+		out = append(out, &f)
+	}
+	return out
+}
@@ -0,0 +1,258 @@
+package p_weather
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// haServer answers like Home Assistant with the given state of
+// weather.home, and with forecast from the get_forecasts service.
+func haServer(t *testing.T, state map[string]interface{}, forecast []map[string]interface{}) (*httptest.Server, *int) {
+	t.Helper()
+	serviceCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "401: Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/config":
+			json.NewEncoder(w).Encode(map[string]float64{"latitude": 52.2, "longitude": 21})
+		case "/api/states/weather.home":
+			json.NewEncoder(w).Encode(state)
+		case "/api/services/weather/get_forecasts":
+			serviceCalls++
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if r.Method != http.MethodPost || body["entity_id"] != "weather.home" || body["type"] != "hourly" {
+				t.Errorf("get_forecasts called with %s %v", r.Method, body)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"service_response": map[string]interface{}{"weather.home": map[string]interface{}{"forecast": forecast}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &serviceCalls
+}
+
+func haForecast(start time.Time, temps ...float64) []map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for i, temp := range temps {
+		entries = append(entries, map[string]interface{}{
+			"datetime": start.Add(time.Duration(i) * 3 * time.Hour).Format(time.RFC3339), "condition": "rainy",
+			"temperature": temp, "humidity": 80.0, "precipitation": 1.0, "precipitation_probability": 60.0,
+		})
+	}
+	return entries
+}
+
+func TestHomeAssistantFetch(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	attrs := map[string]interface{}{"temperature": 50.0, "humidity": 60.0, "pressure": 1013.0,
+		"wind_speed": 10.0, "wind_bearing": 270.0, "temperature_unit": "°F", "wind_speed_unit": "mph"}
+	withForecast := map[string]interface{}{}
+	for k, v := range attrs {
+		withForecast[k] = v
+	}
+	noTemp := haForecast(now.Add(9*time.Hour), 0)
+	delete(noTemp[0], "temperature")
+	withForecast["forecast"] = append(haForecast(now.Add(3*time.Hour), 55, 59), noTemp...)
+
+	tests := []struct {
+		name         string
+		state        map[string]interface{}
+		forecast     []map[string]interface{}
+		serviceCalls int
+		wantTemps    []float64 // °C, current first
+		wantErr      bool
+	}{
+		{"forecast attribute", map[string]interface{}{"state": "cloudy", "attributes": withForecast,
+			"last_updated": now.Format(time.RFC3339)}, nil, 0, []float64{10, 12.78, 15}, false},
+		{"get_forecasts service", map[string]interface{}{"state": "cloudy", "attributes": attrs,
+			"last_updated": now.Format(time.RFC3339)}, haForecast(now.Add(3*time.Hour), 41), 1, []float64{10, 5}, false},
+		{"unavailable", map[string]interface{}{"state": "unavailable", "attributes": map[string]interface{}{}}, nil, 0, nil, true},
+		{"unknown", map[string]interface{}{"state": "unknown", "attributes": attrs}, nil, 0, nil, true},
+		{"no current temperature", map[string]interface{}{"state": "sunny", "attributes": map[string]interface{}{"humidity": 50.0}},
+			haForecast(now, 10), 1, nil, true},
+	}
+	for _, tt := range tests {
+		srv, calls := haServer(t, tt.state, tt.forecast)
+		ha := NewHomeAssistant(srv.URL+"/", "secret", "weather.home", UNITS_METRIC)
+		ws, err := ha.Fetch()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if *calls != tt.serviceCalls {
+			t.Errorf("%s: get_forecasts called %d times, want %d", tt.name, *calls, tt.serviceCalls)
+		}
+		if err != nil {
+			continue
+		}
+		if ws.Latitude != 52.2 || ws.Longitude != 21 {
+			t.Errorf("%s: place %v,%v", tt.name, ws.Latitude, ws.Longitude)
+		}
+		if len(ws.F) != len(tt.wantTemps) {
+			t.Errorf("%s: %d entries, want %d", tt.name, len(ws.F), len(tt.wantTemps))
+			continue
+		}
+		for i, f := range ws.F {
+			if math.Abs(f.Temp-tt.wantTemps[i]) > 0.01 {
+				t.Errorf("%s: entry %d is %.2f°C, want %.2f°C", tt.name, i, f.Temp, tt.wantTemps[i])
+			}
+		}
+		curr := ws.GetCurr()
+		if !curr.T.Equal(now) || curr.ID != 804 || curr.Humidity != 60 || math.Abs(curr.Windspeed-4.47) > 0.01 || curr.Winddeg != 270 {
+			t.Errorf("%s: current %v", tt.name, curr)
+		}
+		if f := ws.F[1]; f.ID != 500 || f.Rain != 1 || f.Pop != 0.6 {
+			t.Errorf("%s: forecast %v", tt.name, f)
+		}
+	}
+}
+
+func TestHomeAssistantCondition(t *testing.T) {
+	ha := NewHomeAssistant("", "", "weather.home", UNITS_METRIC)
+	data := map[string]interface{}{"temperature": 20.0}
+	for condition, id := range HA_CONDITIONS {
+		if f := ha.weatherInfo(time.Now(), condition, data, nil); f.ID != id {
+			t.Errorf("%s: ID %d, want %d", condition, f.ID, id)
+		}
+	}
+	f := ha.weatherInfo(time.Now(), "sandstorm-ish", data, nil)
+	if f.ID != 804 || f.Clouds != 100 {
+		t.Errorf("unknown condition: ID %d with %d%% clouds, want 804 with 100%%", f.ID, f.Clouds)
+	}
+}
+
+func TestHomeAssistantPop(t *testing.T) {
+	ha := NewHomeAssistant("", "", "weather.home", UNITS_METRIC)
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		pop  float64
+	}{
+		{"dry", map[string]interface{}{"temperature": 20.0}, 0},
+		{"raining now", map[string]interface{}{"temperature": 20.0, "precipitation": 1.5}, 1},
+		{"nothing falling", map[string]interface{}{"temperature": 20.0, "precipitation": 0.0}, 0},
+		{"forecast probability", map[string]interface{}{"temperature": 20.0, "precipitation": 1.5, "precipitation_probability": 30.0}, 0.3},
+	}
+	for _, tt := range tests {
+		if f := ha.weatherInfo(time.Now(), "rainy", tt.data, nil); f.Pop != tt.pop {
+			t.Errorf("%s: pop %v, want %v", tt.name, f.Pop, tt.pop)
+		}
+	}
+}
+
+func TestHomeAssistantDewPoint(t *testing.T) {
+	ha := NewHomeAssistant("", "", "weather.home", UNITS_IMPERIAL)
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		dew  float64 // °C, or the sentinel
+	}{
+		{"from humidity", map[string]interface{}{"temperature": 20.0, "humidity": 50.0}, dewPoint(20, 50)},
+		{"from the entity", map[string]interface{}{"temperature": 20.0, "humidity": 50.0, "dew_point": 8.0}, 8},
+		{"no humidity", map[string]interface{}{"temperature": 20.0}, dewPoint(0, 0)},
+		{"no humidity but a dew point", map[string]interface{}{"temperature": 20.0, "dew_point": 8.0}, dewPoint(0, 0)},
+	}
+	for _, tt := range tests {
+		f := ha.weatherInfo(time.Now(), "sunny", tt.data, map[string]interface{}{})
+		if want := UNITS_IMPERIAL.FromCelsius(tt.dew); math.Abs(f.DewPoint-want) > 0.01 {
+			t.Errorf("%s: dew point %.2f, want %.2f", tt.name, f.DewPoint, want)
+		}
+		if f.HasDewPoint != (tt.dew != dewPoint(0, 0)) {
+			t.Errorf("%s: HasDewPoint is %v", tt.name, f.HasDewPoint)
+		}
+	}
+	if f := ha.weatherInfo(time.Now(), "sunny", map[string]interface{}{"humidity": 50.0}, nil); f != nil {
+		t.Errorf("entry without a temperature mapped to %v", f)
+	}
+}
+
+func TestHaToMetric(t *testing.T) {
+	tests := []struct {
+		v    float64
+		unit string
+		want float64
+	}{
+		{20, "°C", 20},
+		{68, "°F", 20},
+		{293.15, "K", 20},
+		{36, "km/h", 10},
+		{10, "mph", 4.4704},
+		{10, "kn", 5.14444},
+		{10, "ft/s", 3.048},
+		{10, "m/s", 10},
+		{30, "inHg", 1015.917},
+		{760, "mmHg", 1013.247},
+		{101.3, "kPa", 1013},
+		{14.7, "psi", 1013.53},
+		{1013, "hPa", 1013},
+		{1013, "mbar", 1013},
+		{1, "in", 25.4},
+		{3, "mm", 3},
+	}
+	for _, tt := range tests {
+		if got := haToMetric(tt.v, tt.unit); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("haToMetric(%v, %s) = %v, want %v", tt.v, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	hourly := func(n int) []*WeatherInfo {
+		entries := []*WeatherInfo{}
+		for i := 0; i < n; i++ {
+			entries = append(entries, &WeatherInfo{T: start.Add(time.Duration(i) * time.Hour), Temp: float64(i),
+				Rain: 1, Pop: float64(i) / 10, WindGust: float64(10 - i)})
+		}
+		return entries
+	}
+
+	got := resample(hourly(7))
+	want := []struct {
+		hour       int
+		temp, rain float64
+		pop, gust  float64
+	}{
+		{1, 1, 3, 0.2, 10},
+		{4, 4, 3, 0.5, 7},
+		{6, 6, 1, 0.6, 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("resampled to %d periods, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		f := got[i]
+		if !f.T.Equal(start.Add(time.Duration(w.hour)*time.Hour)) || f.Temp != w.temp || f.Rain != w.rain || f.Pop != w.pop || f.WindGust != w.gust {
+			t.Errorf("period %d: %s %v°, rain %v, pop %v, gust %v; want hour %d", i, f.T, f.Temp, f.Rain, f.Pop, f.WindGust, w.hour)
+		}
+	}
+
+	in := hourly(7)
+	resample(in)
+	if in[1].Rain != 1 {
+		t.Errorf("resample changed its input")
+	}
+
+	threeHourly := []*WeatherInfo{{T: start}, {T: start.Add(3 * time.Hour)}, {T: start.Add(6 * time.Hour)}}
+	if got := resample(threeHourly); len(got) != 3 || got[1] != threeHourly[1] {
+		t.Errorf("3-hourly forecast resampled")
+	}
+	daily := []*WeatherInfo{{T: start}, {T: start.Add(24 * time.Hour)}}
+	if got := resample(daily); len(got) != 2 {
+		t.Errorf("daily forecast resampled")
+	}
+	if got := resample(hourly(1)); len(got) != 1 {
+		t.Errorf("single entry resampled")
+	}
+}
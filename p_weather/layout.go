@@ -0,0 +1,116 @@
+package p_weather
+
+import (
+	"image"
+)
+
+type Label struct {
+	Text  string
+	X, Y  int
+	Align int
+}
+
+// Layout collects the bounding boxes of everything drawn on the canvas
+// and places text labels where they do not overlap them.
+type Layout struct {
+	MAXNUDGE int
+	bounds   image.Rectangle
+	boxes    []image.Rectangle
+	labels   []*Label
+}
+
+func NewLayout(bounds image.Rectangle) *Layout {
+	return &Layout{
+		MAXNUDGE: 12,
+		bounds:   bounds,
+	}
+}
+
+func (l *Layout) AddBox(r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	l.boxes = append(l.boxes, r)
+}
+
+func (l *Layout) AddLabel(text string, x, y, align int) {
+	l.labels = append(l.labels, &Label{Text: text, X: x, Y: y, Align: align})
+}
+
+func (l *Layout) labelRect(lb *Label, x, y, w, h int) image.Rectangle {
+	switch lb.Align {
+	case ALIGN_RIGHT:
+		x -= w
+	case ALIGN_CENTER:
+		x -= w / 2
+	}
+	return image.Rect(x, y-h, x+w, y)
+}
+
+func (l *Layout) isFree(r image.Rectangle, placed []image.Rectangle) bool {
+	if !r.In(l.bounds) {
+		return false
+	}
+	for _, b := range l.boxes {
+		if r.Overlaps(b) {
+			return false
+		}
+	}
+	for _, b := range placed {
+		if r.Overlaps(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve moves every label to the closest free position, trying
+// vertical nudges before horizontal ones. A label that cannot be freed
+// is only pulled back inside the canvas.
+func (l *Layout) Resolve(s *Sprites) {
+	h := s.TextHeight()
+	placed := []image.Rectangle{}
+	for _, lb := range l.labels {
+		w := s.TextWidth(lb.Text)
+		r := l.labelRect(lb, lb.X, lb.Y, w, h)
+		if !l.isFree(r, placed) {
+			found := false
+			for d := 1; d <= l.MAXNUDGE && !found; d++ {
+				for _, o := range []image.Point{{0, d}, {0, -d}, {d, 0}, {-d, 0}} {
+					c := r.Add(o)
+					if l.isFree(c, placed) {
+						lb.X += o.X
+						lb.Y += o.Y
+						r = c
+						found = true
+						break
+					}
+				}
+			}
+			if !found {
+				dx, dy := 0, 0
+				if r.Min.X < l.bounds.Min.X {
+					dx = l.bounds.Min.X - r.Min.X
+				} else if r.Max.X > l.bounds.Max.X {
+					dx = l.bounds.Max.X - r.Max.X
+				}
+				if r.Min.Y < l.bounds.Min.Y {
+					dy = l.bounds.Min.Y - r.Min.Y
+				} else if r.Max.Y > l.bounds.Max.Y {
+					dy = l.bounds.Max.Y - r.Max.Y
+				}
+				lb.X += dx
+				lb.Y += dy
+				r = r.Add(image.Pt(dx, dy))
+			}
+		}
+		placed = append(placed, r)
+	}
+}
+
+func (l *Layout) Draw(s *Sprites) {
+	l.Resolve(s)
+	for _, lb := range l.labels {
+		s.DrawText(lb.Text, lb.X, lb.Y, lb.Align)
+	}
+}
@@ -0,0 +1,63 @@
+package p_weather
+
+import (
+	"image"
+	"testing"
+)
+
+func TestLayoutResolve(t *testing.T) {
+	type label struct {
+		text  string
+		x, y  int
+		align int
+	}
+	tests := []struct {
+		name     string
+		maxnudge int
+		boxes    []image.Rectangle
+		labels   []label
+		want     []image.Point
+	}{
+		{"free label stays", 12, nil,
+			[]label{{"8", 10, 20, ALIGN_LEFT}},
+			[]image.Point{{10, 20}}},
+		{"takes the smallest nudge", 12, []image.Rectangle{image.Rect(10, 15, 13, 20)},
+			[]label{{"8", 10, 20, ALIGN_LEFT}},
+			[]image.Point{{13, 20}}},
+		{"right aligned label", 12, []image.Rectangle{image.Rect(7, 15, 10, 20)},
+			[]label{{"8", 10, 20, ALIGN_RIGHT}},
+			[]image.Point{{13, 20}}},
+		{"moves below a wide box", 12, []image.Rectangle{image.Rect(0, 15, 100, 20)},
+			[]label{{"8", 10, 20, ALIGN_LEFT}},
+			[]image.Point{{10, 25}}},
+		{"vertical before horizontal", 12, []image.Rectangle{image.Rect(10, 16, 13, 18)},
+			[]label{{"8", 10, 20, ALIGN_LEFT}},
+			[]image.Point{{10, 23}}},
+		{"labels avoid each other", 12, nil,
+			[]label{{"8", 10, 20, ALIGN_LEFT}, {"8", 10, 20, ALIGN_LEFT}},
+			[]image.Point{{10, 20}, {13, 20}}},
+		{"moves back inside the canvas", 12, nil,
+			[]label{{"8", 98, 20, ALIGN_LEFT}},
+			[]image.Point{{97, 20}}},
+		{"pulled inside without a free spot", 0, nil,
+			[]label{{"8", -2, 3, ALIGN_LEFT}},
+			[]image.Point{{0, 5}}},
+	}
+	s := newTestSprites()
+	for _, tt := range tests {
+		l := NewLayout(image.Rect(0, 0, 100, 50))
+		l.MAXNUDGE = tt.maxnudge
+		for _, b := range tt.boxes {
+			l.AddBox(b)
+		}
+		for _, lb := range tt.labels {
+			l.AddLabel(lb.text, lb.x, lb.y, lb.align)
+		}
+		l.Resolve(s)
+		for i, want := range tt.want {
+			if got := image.Pt(l.labels[i].X, l.labels[i].Y); got != want {
+				t.Errorf("%s: label %d at %v, want %v", tt.name, i, got, want)
+			}
+		}
+	}
+}
@@ -1,9 +1,10 @@
-package main
+package p_weather
 
 import (
     "encoding/json"
     "fmt"
     "io/ioutil"
+    "math"
     "net/http"
     "os"
     "path/filepath"
@@ -27,64 +28,168 @@ type WeatherInfo struct {
     Clouds    int
     Rain      float64
     Snow      float64
+    Pop       float64
     Windspeed float64
+    WindGust  float64
     Winddeg   float64
     Temp      float64
+    Pressure  float64
+    Humidity  int
+    DewPoint  float64
+    HasDewPoint bool // false without a humidity reading to derive DewPoint from
+    FeelsLike float64
+    Spread    float64 // temperature disagreement of blended providers
+    Units     Units
 }
 
-func NewWeatherInfo(fdata map[string]interface{}) (*WeatherInfo, error) {
-    t := time.Unix(int64(fdata["dt"].(float64)), 0)
-    id := int(fdata["weather"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+// owmValue looks up a number in one of the objects of a response entry.
+// Objects like rain and snow are left out when there is nothing to report.
+func owmValue(fdata map[string]interface{}, object, key string) (float64, bool) {
+    m, ok := fdata[object].(map[string]interface{})
+    if !ok {
+        return 0, false
+    }
+    val, ok := m[key].(float64)
+    return val, ok
+}
+
+func NewWeatherInfo(fdata map[string]interface{}, units Units) (*WeatherInfo, error) {
+    dt, ok := fdata["dt"].(float64)
+    if !ok {
+        return nil, fmt.Errorf("no time in weather data")
+    }
+    t := time.Unix(int64(dt), 0)
+
+    id := 800
+    if weather, ok := fdata["weather"].([]interface{}); ok && len(weather) > 0 {
+        if w, ok := weather[0].(map[string]interface{}); ok {
+            if val, ok := w["id"].(float64); ok {
+                id = int(val)
+            }
+        }
+    }
 
     clouds := 0
-    if val, ok := fdata["clouds"].(map[string]interface{})["all"]; ok {
-        clouds = int(val.(float64))
+    if val, ok := owmValue(fdata, "clouds", "all"); ok {
+        clouds = int(val)
     }
 
     rain := 0.0
-    if val, ok := fdata["rain"].(map[string]interface{})["3h"]; ok {
-        rain = val.(float64)
+    if val, ok := owmValue(fdata, "rain", "3h"); ok {
+        rain = val
     }
 
     snow := 0.0
-    if val, ok := fdata["snow"].(map[string]interface{})["3h"]; ok {
-        snow = val.(float64)
+    if val, ok := owmValue(fdata, "snow", "3h"); ok {
+        snow = val
+    }
+
+    // the current weather has no pop: what falls now is certain
+    pop := 0.0
+    if val, ok := fdata["pop"].(float64); ok {
+        pop = val
+    } else if rain+snow > 0 {
+        pop = 1
     }
 
     windspeed := 0.0
-    if val, ok := fdata["wind"].(map[string]interface{})["speed"]; ok {
-        windspeed = val.(float64)
+    if val, ok := owmValue(fdata, "wind", "speed"); ok {
+        windspeed = val
+    }
+
+    windgust := windspeed
+    if val, ok := owmValue(fdata, "wind", "gust"); ok {
+        windgust = val
     }
 
     winddeg := 0.0
-    if val, ok := fdata["wind"].(map[string]interface{})["deg"]; ok {
-        winddeg = val.(float64)
+    if val, ok := owmValue(fdata, "wind", "deg"); ok {
+        winddeg = val
+    }
+
+    temp, ok := owmValue(fdata, "main", "temp")
+    if !ok {
+        return nil, fmt.Errorf("no temperature in weather data for %s", t)
+    }
+    temp -= KTOC
+
+    pressure := 0.0
+    if val, ok := owmValue(fdata, "main", "pressure"); ok {
+        pressure = val
+    }
+
+    humidity := 0
+    if val, ok := owmValue(fdata, "main", "humidity"); ok {
+        humidity = int(val)
+    }
+
+    feelslike := apparentTemp(temp, windspeed, humidity)
+    if val, ok := owmValue(fdata, "main", "feels_like"); ok {
+        feelslike = val - KTOC
+    }
+
+    return &WeatherInfo{T: t, ID: id, Clouds: clouds, Rain: units.FromMillimetres(rain), Snow: units.FromMillimetres(snow), Pop: pop,
+        Windspeed: units.FromMetresPerSecond(windspeed), WindGust: units.FromMetresPerSecond(windgust), Winddeg: winddeg,
+        Temp: units.FromCelsius(temp), Pressure: pressure, Humidity: humidity,
+        DewPoint: units.FromCelsius(dewPoint(temp, humidity)), HasDewPoint: humidity > 0, FeelsLike: units.FromCelsius(feelslike), Units: units}, nil
+}
+
+// dewPoint uses the Magnus formula; without a humidity reading the dew
+// point is taken to be far below the air temperature.
+func dewPoint(temp float64, humidity int) float64 {
+    if humidity <= 0 {
+        return -KTOC
+    }
+    const b, c = 17.62, 243.12
+    gamma := math.Log(float64(humidity)/100.0) + b*temp/(c+temp)
+    return c * gamma / (b - gamma)
+}
+
+// apparentTemp falls back to the wind chill in cold windy weather and
+// the heat index in hot humid weather when the provider has no
+// feels-like value.
+func apparentTemp(temp, windspeed float64, humidity int) float64 {
+    if temp <= 10 && windspeed > 1.34 {
+        v := math.Pow(windspeed*3.6, 0.16)
+        return 13.12 + 0.6215*temp - 11.37*v + 0.3965*temp*v
     }
+    if temp >= 27 && humidity >= 40 {
+        t := temp*9/5 + 32
+        r := float64(humidity)
+        hi := -42.379 + 2.04901523*t + 10.14333127*r - 0.22475541*t*r - 0.00683783*t*t -
+            0.05481717*r*r + 0.00122874*t*t*r + 0.00085282*t*r*r - 0.00000199*t*t*r*r
+        return (hi - 32) * 5 / 9
+    }
+    return temp
+}
 
-    temp := fdata["main"].(map[string]interface{})["temp"].(float64) - KTOC
+func (w *WeatherInfo) IsMixed() bool {
+    if w.ID == 511 || (w.ID >= 611 && w.ID <= 616) {
+        return true
+    }
+    return w.Rain > 0 && w.Snow > 0
+}
 
-    return &WeatherInfo{T: t, ID: id, Clouds: clouds, Rain: rain, Snow: snow, Windspeed: windspeed, Winddeg: winddeg, Temp: temp}, nil
+func (w *WeatherInfo) IsFreezingRain() bool {
+    return w.ID == 511 || (w.Rain > 0 && w.Units.Celsius(w.Temp) < 0)
 }
 
 func (w *WeatherInfo) Print() {
-    fmt.Printf("%s %d %03d%% %.2f %.2f %+.2f (%5.1f,%03d)\n",
-        w.T, w.ID, w.Clouds, w.Rain, w.Snow, w.Temp, w.Windspeed, int(w.Winddeg))
+    fmt.Printf("%s %d %03d%% %.2f %.2f %03d%% %+.2f (%5.1f/%5.1f,%03d) %6.1f %03d%% %+.1f %+.2f\n",
+        w.T, w.ID, w.Clouds, w.Rain, w.Snow, int(w.Pop*100), w.Temp, w.Windspeed, w.WindGust, int(w.Winddeg), w.Pressure, w.Humidity, w.DewPoint, w.FeelsLike)
 }
 
 type OpenWeatherMap struct {
-    Latitude  float64
-    Longitude float64
+    WeatherSeries
     Rootdir   string
-    F         []*WeatherInfo
     URL_FORECAST string
     URL_CURR      string
     PLACEKEY      string
 }
 
-func NewOpenWeatherMap(apikey string, latitude, longitude float64, rootdir string) *OpenWeatherMap {
+func NewOpenWeatherMap(apikey string, latitude, longitude float64, rootdir string, units Units) *OpenWeatherMap {
     owm := &OpenWeatherMap{
-        Latitude:  latitude,
-        Longitude: longitude,
+        WeatherSeries: WeatherSeries{Latitude: latitude, Longitude: longitude, Units: units},
         Rootdir:   rootdir,
     }
     owm.URL_FORECAST = fmt.Sprintf("%sforecast?lat=%.4f&lon=%.4f&mode=json&APPID=%s", OWMURL, latitude, longitude, apikey)
@@ -94,13 +199,26 @@ func NewOpenWeatherMap(apikey string, latitude, longitude float64, rootdir strin
         os.Mkdir(owm.Rootdir, os.ModePerm)
     }
 
-    owm.PLACEKEY = owm.makePlaceKey()
+    owm.PLACEKEY = PlaceKey(latitude, longitude)
 
     return owm
 }
 
-func (owm *OpenWeatherMap) makePlaceKey() string {
-    return makeCoordinateKey(owm.Latitude) + makeCoordinateKey(owm.Longitude)
+func (owm *OpenWeatherMap) Name() string {
+    return PROVIDER_OWM
+}
+
+// Fetch loads the responses, from the cache while it is fresh.
+func (owm *OpenWeatherMap) Fetch() (*WeatherSeries, error) {
+    if err := owm.fromAuto(); err != nil {
+        return nil, err
+    }
+    return &owm.WeatherSeries, nil
+}
+
+// PlaceKey names the cache and archive files of a location.
+func PlaceKey(latitude, longitude float64) string {
+    return makeCoordinateKey(latitude) + makeCoordinateKey(longitude)
 }
 
 func makeCoordinateKey(p float64) string {
@@ -147,24 +265,31 @@ func (owm *OpenWeatherMap) fetchFromWWW() error {
 }
 
 func (owm *OpenWeatherMap) fromJSON(data_curr, data_fcst map[string]interface{}) error {
-    owm.F = nil
-    f, err := NewWeatherInfo(data_curr)
+    F, err := owm.parseJSON(data_curr, data_fcst)
+    owm.F = F
+    return err
+}
+
+// parseJSON returns the current observation followed by the forecast.
+func (owm *OpenWeatherMap) parseJSON(data_curr, data_fcst map[string]interface{}) ([]*WeatherInfo, error) {
+    F := []*WeatherInfo{}
+    f, err := NewWeatherInfo(data_curr, owm.Units)
     if err != nil {
-        return err
+        return F, err
     }
-    owm.F = append(owm.F, f)
+    F = append(F, f)
 
     list, ok := data_fcst["list"].([]interface{})
     if !ok {
-        return fmt.Errorf("no forecast data available")
+        return F, fmt.Errorf("no forecast data available")
     }
 
     for _, fdata := range list {
-        if info, err := NewWeatherInfo(fdata.(map[string]interface{})); err == nil {
-            owm.F = append(owm.F, info)
+        if info, err := NewWeatherInfo(fdata.(map[string]interface{}), owm.Units); err == nil {
+            F = append(F, info)
         }
     }
-    return nil
+    return F, nil
 }
 
 func (owm *OpenWeatherMap) isFileTooOld(filename string) bool {
@@ -215,40 +340,8 @@ func (owm *OpenWeatherMap) fromFile() error {
     return owm.fromJSON(current, forecast)
 }
 
-func (owm *OpenWeatherMap) getCurr() *WeatherInfo {
-    if len(owm.F) == 0 {
-        return nil
-    }
-    return owm.F[0]
-}
-
-func (owm *OpenWeatherMap) get(time time.Time) *WeatherInfo {
-    for _, f := range owm.F {
-        if f.T.After(time) {
-            return f
-        }
-    }
-    return nil
-}
-
 func (owm *OpenWeatherMap) printAll() {
     for _, f := range owm.F {
         f.Print()
     }
 }
-
-func main() {
-    // Example usage
-    apikey := "your_api_key"
-    latitude := -33.8651
-    longitude := 151.2099
-    rootdir := "./weather_data"
-
-    owm := NewOpenWeatherMap(apikey, latitude, longitude, rootdir)
-
-    if err := owm.fromAuto(); err != nil {
-        fmt.Println("Error:", err)
-    } else {
-        owm.printAll()
-    }
-}
@@ -0,0 +1,181 @@
+package p_weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsMixed(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         int
+		rain, snow float64
+		want       bool
+	}{
+		{"freezing rain code", 511, 0, 0, true},
+		{"sleet code", 611, 0, 0, true},
+		{"rain and snow code", 616, 0, 0, true},
+		{"rain and snow amounts", 500, 1, 0.5, true},
+		{"rain only", 500, 1, 0, false},
+		{"snow only", 600, 0, 1, false},
+		{"heavy snow code", 602, 0, 2, false},
+		{"clear", 800, 0, 0, false},
+	}
+	for _, tt := range tests {
+		f := &WeatherInfo{ID: tt.id, Rain: tt.rain, Snow: tt.snow}
+		if got := f.IsMixed(); got != tt.want {
+			t.Errorf("%s: IsMixed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsFreezingRain(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    int
+		rain  float64
+		temp  float64
+		units Units
+		want  bool
+	}{
+		{"freezing rain code", 511, 0, 5, UNITS_METRIC, true},
+		{"rain below zero", 500, 1, -2, UNITS_METRIC, true},
+		{"rain above zero", 500, 1, 2, UNITS_METRIC, false},
+		{"no rain below zero", 600, 0, -2, UNITS_METRIC, false},
+		{"rain at 28F", 500, 0.1, 28, UNITS_IMPERIAL, true},
+		{"rain at 40F", 500, 0.1, 40, UNITS_IMPERIAL, false},
+		{"rain at 270K", 500, 1, 270, UNITS_SI, true},
+	}
+	for _, tt := range tests {
+		f := &WeatherInfo{ID: tt.id, Rain: tt.rain, Temp: tt.temp, Units: tt.units}
+		if got := f.IsFreezingRain(); got != tt.want {
+			t.Errorf("%s: IsFreezingRain() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		temp     float64
+		humidity int
+		want     float64
+	}{
+		{20, 50, 9.26},
+		{25, 100, 25},
+		{0, 80, -3.04},
+		{30, 70, 23.93},
+		{-10, 60, -16.31},
+		{15, 10, -16.45},
+		{20, 0, -KTOC}, // no humidity reading
+	}
+	for _, tt := range tests {
+		if got := dewPoint(tt.temp, tt.humidity); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("dewPoint(%v, %d) = %.2f, want %.2f", tt.temp, tt.humidity, got, tt.want)
+		}
+	}
+}
+
+func TestApparentTemp(t *testing.T) {
+	tests := []struct {
+		name      string
+		temp      float64
+		windspeed float64
+		humidity  int
+		want      float64
+	}{
+		{"wind chill at 0C", 0, 5, 80, -4.94},
+		{"wind chill at -10C", -10, 10, 50, -20.30},
+		{"wind chill at 10C", 10, 3, 80, 8.50},
+		{"calm at 10C", 10, 1.34, 50, 10},
+		{"mild and windy", 20, 10, 90, 20},
+		{"heat index", 30, 2, 70, 35.04},
+		{"heat index at 35C", 35, 0, 50, 40.68},
+		{"hot and dry", 30, 5, 30, 30},
+	}
+	for _, tt := range tests {
+		if got := apparentTemp(tt.temp, tt.windspeed, tt.humidity); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: apparentTemp(%v, %v, %d) = %.2f, want %.2f", tt.name, tt.temp, tt.windspeed, tt.humidity, got, tt.want)
+		}
+	}
+}
+
+func TestHasDewPoint(t *testing.T) {
+	tests := []struct {
+		temp     float64
+		humidity int
+		units    Units
+		want     bool
+	}{
+		{20, 50, UNITS_METRIC, true},
+		{20, 0, UNITS_METRIC, false},
+		{68, 50, UNITS_IMPERIAL, true},
+		{68, 0, UNITS_IMPERIAL, false},
+		{293.15, 0, UNITS_SI, false},
+	}
+	for _, tt := range tests {
+		m := map[string]interface{}{"temp": tt.units.Celsius(tt.temp) + KTOC}
+		if tt.humidity > 0 {
+			m["humidity"] = float64(tt.humidity)
+		}
+		f, err := NewWeatherInfo(map[string]interface{}{"dt": 1.0, "main": m}, tt.units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.HasDewPoint != tt.want {
+			t.Errorf("%v %v %d%%: HasDewPoint = %v, want %v", tt.units, tt.temp, tt.humidity, f.HasDewPoint, tt.want)
+		}
+	}
+}
+
+func TestNewWeatherInfoPop(t *testing.T) {
+	tests := []struct {
+		name  string
+		fdata map[string]interface{}
+		want  float64
+	}{
+		{"forecast pop", map[string]interface{}{"pop": 0.2, "rain": map[string]interface{}{"3h": 3.0}}, 0.2},
+		{"no pop, dry", map[string]interface{}{}, 0},
+		{"no pop, raining", map[string]interface{}{"rain": map[string]interface{}{"3h": 0.5}}, 1},
+		{"no pop, snowing", map[string]interface{}{"snow": map[string]interface{}{"3h": 1.0}}, 1},
+		{"zero pop", map[string]interface{}{"pop": 0.0}, 0},
+	}
+	for _, tt := range tests {
+		tt.fdata["dt"] = 1760000000.0
+		tt.fdata["main"] = map[string]interface{}{"temp": 283.15}
+		f, err := NewWeatherInfo(tt.fdata, UNITS_METRIC)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if f.Pop != tt.want {
+			t.Errorf("%s: Pop = %v, want %v", tt.name, f.Pop, tt.want)
+		}
+	}
+}
+
+func TestNewWeatherInfoGust(t *testing.T) {
+	tests := []struct {
+		name     string
+		wind     map[string]interface{}
+		units    Units
+		speed    float64
+		wantGust float64
+	}{
+		{"gust reported", map[string]interface{}{"speed": 5.0, "gust": 20.0}, UNITS_METRIC, 5, 20},
+		{"no gust", map[string]interface{}{"speed": 5.0}, UNITS_METRIC, 5, 5},
+		{"no gust, imperial", map[string]interface{}{"speed": 10.0}, UNITS_IMPERIAL, 22.37, 22.37},
+		{"no wind", nil, UNITS_METRIC, 0, 0},
+	}
+	for _, tt := range tests {
+		fdata := map[string]interface{}{"dt": 1760000000.0, "main": map[string]interface{}{"temp": 283.15}}
+		if tt.wind != nil {
+			fdata["wind"] = tt.wind
+		}
+		f, err := NewWeatherInfo(fdata, tt.units)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if math.Abs(f.Windspeed-tt.speed) > 0.01 || math.Abs(f.WindGust-tt.wantGust) > 0.01 {
+			t.Errorf("%s: wind %v gusting %v, want %v gusting %v", tt.name, f.Windspeed, f.WindGust, tt.speed, tt.wantGust)
+		}
+	}
+}
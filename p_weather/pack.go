@@ -0,0 +1,116 @@
+package p_weather
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+)
+
+const PACK_MANIFEST = "pack.json"
+
+// SpriteRole tells which sprites play a part of the landscape. Index
+// lists the sprite indexes by level (wind strength, cloud coverage,
+// glyph). Anchor shifts the sprite from the point it is drawn at and
+// Anchors names points relative to that point, e.g. the house chimney.
+type SpriteRole struct {
+	Sprite  string            `json:"sprite"`
+	Index   []int             `json:"index"`
+	Anchor  [2]int            `json:"anchor"`
+	Anchors map[string][2]int `json:"anchors"`
+	SnowCap bool              `json:"snowcap"`
+}
+
+// SpritePack is the manifest of a sprite pack. Colors gives the colour
+// used in the sprite files for ink (drawn black), paper (drawn white)
+// and accent (drawn red where the output supports it).
+type SpritePack struct {
+	Name   string                 `json:"name"`
+	Colors map[string]string      `json:"colors"`
+	Roles  map[string]*SpriteRole `json:"roles"`
+
+	ink, paper, accent color.Color
+}
+
+var REQUIRED_ROLES = []string{
+	"house", "sun", "moon", "flower.midnight", "flower.noon",
+	"wind.N", "wind.E", "wind.S", "wind.W", "cloud", "digit",
+}
+
+func LoadSpritePack(fsys fs.FS) (*SpritePack, error) {
+	data, err := fs.ReadFile(fsys, PACK_MANIFEST)
+	if err != nil {
+		return nil, err
+	}
+	p := &SpritePack{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", PACK_MANIFEST, err)
+	}
+
+	if err := p.parseColors(); err != nil {
+		return nil, fmt.Errorf("%s: %w", PACK_MANIFEST, err)
+	}
+	return p, nil
+}
+
+func (p *SpritePack) parseColors() error {
+	colors := map[string]*color.Color{"ink": &p.ink, "paper": &p.paper, "accent": &p.accent}
+	defaults := map[string]string{"ink": "#000000", "paper": "#ffffff", "accent": "#ff0000"}
+	for name, c := range colors {
+		hex, ok := p.Colors[name]
+		if !ok {
+			hex = defaults[name]
+		}
+		col, err := parseHexColor(hex)
+		if err != nil {
+			return fmt.Errorf("color '%s': %w", name, err)
+		}
+		*c = col
+	}
+	return nil
+}
+
+func parseHexColor(hex string) (color.Color, error) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return nil, err
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
+
+func (p *SpritePack) Role(role string) *SpriteRole {
+	if r, ok := p.Roles[role]; ok {
+		return r
+	}
+	return &SpriteRole{Sprite: role}
+}
+
+// Level returns the sprite index for a level of the role, clamped to
+// the levels the pack provides.
+func (r *SpriteRole) Level(level int) int {
+	if len(r.Index) == 0 {
+		return level
+	}
+	if level < 0 {
+		level = 0
+	}
+	if level >= len(r.Index) {
+		level = len(r.Index) - 1
+	}
+	return r.Index[level]
+}
+
+func (r *SpriteRole) AnchorPoint(name string) image.Point {
+	a := r.Anchors[name]
+	return image.Pt(a[0], a[1])
+}
+
+func (p *SpritePack) isSnowCapped(sprite string) bool {
+	for _, r := range p.Roles {
+		if r.Sprite == sprite && r.SnowCap {
+			return true
+		}
+	}
+	return false
+}
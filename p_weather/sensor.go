@@ -0,0 +1,190 @@
+package p_weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SensorReading is a measurement of a local sensor in metric units.
+// Quantities the sensor does not measure are left nil.
+type SensorReading struct {
+	T        time.Time `json:"time"`
+	Temp     *float64  `json:"temp"`     // °C
+	Humidity *float64  `json:"humidity"` // %
+	Pressure *float64  `json:"pressure"` // hPa
+}
+
+const (
+	SENSOR_CLOCK_SKEW = 5 * time.Minute // how far ahead a sensor's clock may run
+)
+
+// Validate rejects readings no sensor in the open air gives: values that
+// are not finite or out of range, and times in the future. The pressure
+// range leaves room for the station pressure of mountain sites.
+func (r *SensorReading) Validate() error {
+	check := func(name string, v *float64, lo, hi float64) error {
+		if v == nil {
+			return nil
+		}
+		if math.IsNaN(*v) || math.IsInf(*v, 0) || *v < lo || *v > hi {
+			return fmt.Errorf("%s %v out of range %v..%v", name, *v, lo, hi)
+		}
+		return nil
+	}
+	if err := check("temp", r.Temp, -90, 70); err != nil {
+		return err
+	}
+	if err := check("humidity", r.Humidity, 0, 100); err != nil {
+		return err
+	}
+	if err := check("pressure", r.Pressure, 300, 1100); err != nil {
+		return err
+	}
+	if r.T.After(time.Now().Add(SENSOR_CLOCK_SKEW)) {
+		return fmt.Errorf("reading from the future: %s", r.T.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// SensorSource gives the latest reading of a local sensor, nil if there
+// is none yet.
+type SensorSource interface {
+	Name() string
+	Latest() (*SensorReading, error)
+}
+
+// ParseReading reads JSON like {"temp": 21.5, "humidity": 40,
+// "pressure": 1013}, optionally with a "time", or a bare temperature.
+// Readings that do not validate are rejected.
+func ParseReading(data []byte) (*SensorReading, error) {
+	r := &SensorReading{}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
+		r.Temp = &v // a bare number is a temperature
+	} else if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Temp == nil && r.Humidity == nil && r.Pressure == nil {
+		return nil, errors.New("reading without temp, humidity or pressure")
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FileSensor reads a JSON file that something else keeps up to date,
+// the web server's sensor push endpoint or the MQTT bridge. Without a
+// time in the file the reading is as old as the file.
+type FileSensor struct {
+	Path string
+}
+
+func (s *FileSensor) Name() string {
+	return "file " + s.Path
+}
+
+func (s *FileSensor) Latest() (*SensorReading, error) {
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseReading(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Path, err)
+	}
+	if r.T.IsZero() {
+		r.T = info.ModTime()
+	}
+	return r, nil
+}
+
+// LocalConditions replaces the provider's current observation with the
+// reading of the first sensor that has a recent one. Quantities the
+// sensor lacks stay as the provider observed them.
+type LocalConditions struct {
+	Provider       Provider
+	Sensors        []SensorSource
+	SENSOR_MAX_AGE time.Duration
+}
+
+func NewLocalConditions(provider Provider, sensors ...SensorSource) *LocalConditions {
+	return &LocalConditions{
+		Provider:       provider,
+		Sensors:        sensors,
+		SENSOR_MAX_AGE: 30 * time.Minute,
+	}
+}
+
+func (lc *LocalConditions) Name() string {
+	return lc.Provider.Name()
+}
+
+func (lc *LocalConditions) reading() *SensorReading {
+	for _, s := range lc.Sensors {
+		r, err := s.Latest()
+		if err != nil {
+			fmt.Printf("Sensor %s: %s\n", s.Name(), err)
+			continue
+		}
+		if r == nil {
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			fmt.Printf("Sensor %s: %s\n", s.Name(), err)
+			continue
+		}
+		if time.Since(r.T) <= lc.SENSOR_MAX_AGE {
+			return r
+		}
+	}
+	return nil
+}
+
+func (lc *LocalConditions) Fetch() (*WeatherSeries, error) {
+	ws, err := lc.Provider.Fetch()
+	if err != nil || len(ws.F) == 0 {
+		return ws, err
+	}
+	r := lc.reading()
+	if r == nil {
+		return ws, nil
+	}
+
+	// the provider keeps its series, the copy gets the local reading
+	local := *ws
+	local.F = append([]*WeatherInfo{}, ws.F...)
+	curr := *ws.F[0]
+	local.F[0] = &curr
+
+	u := curr.Units
+	temp := u.Celsius(curr.Temp)
+	if r.Temp != nil {
+		temp = *r.Temp
+		curr.Temp = u.FromCelsius(temp)
+	}
+	if r.Humidity != nil {
+		curr.Humidity = int(*r.Humidity + 0.5)
+	}
+	if r.Pressure != nil {
+		curr.Pressure = *r.Pressure
+	}
+	if r.Temp != nil || r.Humidity != nil {
+		curr.DewPoint = u.FromCelsius(dewPoint(temp, curr.Humidity))
+		curr.HasDewPoint = curr.Humidity > 0
+		curr.FeelsLike = u.FromCelsius(apparentTemp(temp, u.MetresPerSecond(curr.Windspeed), curr.Humidity))
+	}
+	return &local, nil
+}
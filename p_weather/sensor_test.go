@@ -0,0 +1,173 @@
+package p_weather
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseReading(t *testing.T) {
+	tests := []struct {
+		data                     string
+		temp, humidity, pressure float64 // -1 for none
+		wantErr                  bool
+	}{
+		{"21.5", 21.5, -1, -1, false},
+		{" -3\n", -3, -1, -1, false},
+		{`{"temp": 21.5, "humidity": 40, "pressure": 1013}`, 21.5, 40, 1013, false},
+		{`{"humidity": 55}`, -1, 55, -1, false},
+		{`{"time": "2026-01-15T12:00:00Z", "temp": 0}`, 0, -1, -1, false},
+		{`{"time": "2126-01-15T12:00:00Z", "temp": 0}`, 0, 0, 0, true},
+		{"NaN", 0, 0, 0, true},
+		{"+Inf", 0, 0, 0, true},
+		{"500", 0, 0, 0, true},
+		{`{"humidity": 300}`, 0, 0, 0, true},
+		{`{}`, 0, 0, 0, true},
+		{`{"temperature": 20}`, 0, 0, 0, true},
+		{`{"temp": "warm"}`, 0, 0, 0, true},
+		{`[1, 2]`, 0, 0, 0, true},
+		{"warm", 0, 0, 0, true},
+	}
+	value := func(v *float64) float64 {
+		if v == nil {
+			return -1
+		}
+		return *v
+	}
+	for _, tt := range tests {
+		r, err := ParseReading([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseReading(%q) error %v, want error %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if value(r.Temp) != tt.temp || value(r.Humidity) != tt.humidity || value(r.Pressure) != tt.pressure {
+			t.Errorf("ParseReading(%q) = %v/%v/%v, want %v/%v/%v", tt.data,
+				value(r.Temp), value(r.Humidity), value(r.Pressure), tt.temp, tt.humidity, tt.pressure)
+		}
+	}
+}
+
+func TestSensorReadingValidate(t *testing.T) {
+	v := func(f float64) *float64 { return &f }
+	now := time.Now()
+	tests := []struct {
+		name    string
+		r       SensorReading
+		wantErr bool
+	}{
+		{"typical", SensorReading{T: now, Temp: v(21.5), Humidity: v(40), Pressure: v(1013)}, false},
+		{"nothing measured", SensorReading{T: now}, false},
+		{"extremes", SensorReading{T: now, Temp: v(-89), Humidity: v(0), Pressure: v(700)}, false},
+		{"NaN temp", SensorReading{T: now, Temp: v(math.NaN())}, true},
+		{"infinite pressure", SensorReading{T: now, Pressure: v(math.Inf(1))}, true},
+		{"boiling", SensorReading{T: now, Temp: v(500)}, true},
+		{"humidity over 100%", SensorReading{T: now, Humidity: v(300)}, true},
+		{"negative humidity", SensorReading{T: now, Humidity: v(-1)}, true},
+		{"pressure in Pa", SensorReading{T: now, Pressure: v(101300)}, true},
+		{"clock a little ahead", SensorReading{T: now.Add(time.Minute), Temp: v(20)}, false},
+		{"from the future", SensorReading{T: now.Add(time.Hour), Temp: v(20)}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.r.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFileSensor(t *testing.T) {
+	dir := t.TempDir()
+	s := &FileSensor{Path: filepath.Join(dir, "sensor.json")}
+	if r, err := s.Latest(); r != nil || err != nil {
+		t.Errorf("missing file: %v, %v", r, err)
+	}
+
+	os.WriteFile(s.Path, []byte(`{"temp": 20}`), 0644)
+	mtime := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	os.Chtimes(s.Path, mtime, mtime)
+	if r, err := s.Latest(); err != nil || !r.T.Equal(mtime) {
+		t.Errorf("reading without a time: %v, %v, want the file's time %s", r, err, mtime)
+	}
+
+	os.WriteFile(s.Path, []byte(`{"time": "2026-01-15T11:00:00Z", "temp": 20}`), 0644)
+	if r, err := s.Latest(); err != nil || !r.T.Equal(mtime.Add(time.Hour)) {
+		t.Errorf("reading with a time: %v, %v", r, err)
+	}
+
+	os.WriteFile(s.Path, []byte(`{}`), 0644)
+	if _, err := s.Latest(); err == nil {
+		t.Errorf("empty reading did not fail")
+	}
+}
+
+// fakeSensor gives a fixed reading.
+type fakeSensor struct {
+	r   *SensorReading
+	err error
+}
+
+func (s *fakeSensor) Name() string {
+	return "fake"
+}
+
+func (s *fakeSensor) Latest() (*SensorReading, error) {
+	return s.r, s.err
+}
+
+func TestLocalConditions(t *testing.T) {
+	temp, humidity := 5.0, 90.0
+	now := time.Now()
+	fresh := &SensorReading{T: now.Add(-10 * time.Minute), Temp: &temp}
+	stale := &SensorReading{T: now.Add(-31 * time.Minute), Temp: &temp}
+	humid := &SensorReading{T: now, Humidity: &humidity}
+	future := &SensorReading{T: now.Add(time.Hour), Temp: &temp}
+
+	tests := []struct {
+		name     string
+		sensors  []SensorSource
+		temp     float64
+		humidity int
+	}{
+		{"no sensor", nil, 68, 40},
+		{"fresh reading", []SensorSource{&fakeSensor{r: fresh}}, 41, 40},
+		{"stale reading", []SensorSource{&fakeSensor{r: stale}}, 68, 40},
+		{"no reading yet", []SensorSource{&fakeSensor{}}, 68, 40},
+		{"stale then fresh", []SensorSource{&fakeSensor{r: stale}, &fakeSensor{r: fresh}}, 41, 40},
+		{"failing then fresh", []SensorSource{&fakeSensor{err: errors.New("broken")}, &fakeSensor{r: fresh}}, 41, 40},
+		{"humidity only", []SensorSource{&fakeSensor{r: humid}}, 68, 90},
+		{"future reading", []SensorSource{&fakeSensor{r: future}}, 68, 40},
+		{"future then fresh", []SensorSource{&fakeSensor{r: future}, &fakeSensor{r: fresh}}, 41, 40},
+	}
+	for _, tt := range tests {
+		curr := &WeatherInfo{T: now, Temp: 68, Humidity: 40, Units: UNITS_IMPERIAL,
+			DewPoint: UNITS_IMPERIAL.FromCelsius(dewPoint(20, 40)), HasDewPoint: true}
+		ws := &WeatherSeries{Units: UNITS_IMPERIAL, F: []*WeatherInfo{curr, {T: now.Add(3 * time.Hour), Temp: 70}}}
+		lc := NewLocalConditions(&fakeProvider{name: "fake", ws: ws}, tt.sensors...)
+
+		got, err := lc.Fetch()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		f := got.F[0]
+		if f.Temp != tt.temp || f.Humidity != tt.humidity {
+			t.Errorf("%s: current %v°F %d%%, want %v°F %d%%", tt.name, f.Temp, f.Humidity, tt.temp, tt.humidity)
+		}
+		if want := UNITS_IMPERIAL.FromCelsius(dewPoint(UNITS_IMPERIAL.Celsius(f.Temp), f.Humidity)); f.DewPoint-want > 0.01 || want-f.DewPoint > 0.01 {
+			t.Errorf("%s: dew point %v, want %v", tt.name, f.DewPoint, want)
+		}
+		if !f.HasDewPoint {
+			t.Errorf("%s: lost the dew point", tt.name)
+		}
+		if ws.F[0] != curr || curr.Temp != 68 || curr.Humidity != 40 {
+			t.Errorf("%s: the provider's series was changed", tt.name)
+		}
+		if len(got.F) != 2 || got.F[1] != ws.F[1] {
+			t.Errorf("%s: forecast not kept", tt.name)
+		}
+	}
+}
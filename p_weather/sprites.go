@@ -1,12 +1,13 @@
-package main
+package p_weather
 
 import (
+	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	"math"
 	"math/rand"
-	"os"
-	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type Sprites struct {
@@ -16,21 +17,26 @@ type Sprites struct {
 	Trans     color.Color
 	PLASSPRITE int
 	MINUSSPRITE int
+	POPCERTAIN float64
+	TRICOLOR   bool
+	GREYSCALE  bool
+	GUSTDELTA  float64
 	EXT       string
-	img       *image.RGBA
-	dir       string
+	SnowCap   int
+	Layout    *Layout
+	Canvas    Canvas
+	spriteCache map[recolorKey]image.Image
+	rnd       *rand.Rand
+	atlas     *SpriteAtlas
 	w, h      int
 }
 
-func NewSprites(spritesDir string, canvas image.Image) *Sprites {
-	bounds := canvas.Bounds()
-	img := image.NewRGBA(bounds)
-	for x := bounds.Min.X; x < bounds.Max.X; x++ {
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			img.Set(x, y, canvas.At(x, y))
-		}
+func NewSprites(spritesDir string, canvas image.Image) (*Sprites, error) {
+	atlas, err := LoadSpriteAtlas(spritesDir)
+	if err != nil {
+		return nil, fmt.Errorf("sprite pack: %w", err)
 	}
-
+	bounds := canvas.Bounds()
 	return &Sprites{
 		Black:      color.RGBA{0, 0, 0, 255},
 		White:      color.RGBA{255, 255, 255, 255},
@@ -38,29 +44,39 @@ func NewSprites(spritesDir string, canvas image.Image) *Sprites {
 		Trans:      color.RGBA{0, 0, 0, 0},
 		PLASSPRITE: 10,
 		MINUSSPRITE: 11,
+		POPCERTAIN: 0.7,
+		TRICOLOR:   false,
+		GREYSCALE:  false,
+		GUSTDELTA:  5.0,
 		EXT:        ".png",
-		img:       img,
-		dir:       spritesDir,
+		Canvas:    NewRasterCanvas(canvas),
+		atlas:     atlas,
+		spriteCache: map[recolorKey]image.Image{},
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
 		w:         bounds.Max.X,
 		h:         bounds.Max.Y,
-	}
+	}, nil
 }
 
 func (s *Sprites) Dot(x, y int, color color.Color) {
 	if y >= s.h || x >= s.w || y < 0 || x < 0 {
 		return
 	}
-	s.img.Set(x, y, color)
+	s.Canvas.SetPixel(x, y, color)
+}
+
+func (s *Sprites) DrawPath(p *Path, color color.Color, dashed bool) {
+	s.Canvas.DrawPath(p, color, dashed)
 }
 
 func (s *Sprites) Draw(name string, index, xpos, ypos int) int {
-	imageFilename := filepath.Join(s.dir, name+"_"+formatIndex(index)+s.EXT)
-	img, err := loadImage(imageFilename)
-	if err != nil {
+	img := s.atlas.Get(name, index)
+	if img == nil {
 		return 0
 	}
 	w, h := img.Bounds().Max.X, img.Bounds().Max.Y
 	ypos -= h
+	box := image.Rectangle{}
 
 	for x := 0; x < w; x++ {
 		for y := 0; y < h; y++ {
@@ -70,52 +86,213 @@ func (s *Sprites) Draw(name string, index, xpos, ypos int) int {
 			if ypos+y >= s.h || ypos+y < 0 {
 				continue
 			}
-			col := img.At(x, y)
-			if col == s.Black {
-				s.Dot(xpos+x, ypos+y, s.Black)
-			} else if col == s.White {
-				s.Dot(xpos+x, ypos+y, s.White)
-			} else if col == s.Red {
-				s.Dot(xpos+x, ypos+y, s.Black)
+			if s.isOpaque(img.At(x, y)) {
+				box = box.Union(image.Rect(xpos+x, ypos+y, xpos+x+1, ypos+y+1))
 			}
 		}
 	}
+	s.Canvas.BlitSprite(s.recolor(name, img), xpos, ypos)
+	if s.SnowCap > 0 && s.atlas.Pack.isSnowCapped(name) {
+		s.drawSnowCap(img, xpos, ypos, s.SnowCap)
+	}
+	if s.Layout != nil && name != s.atlas.Pack.Role("digit").Sprite {
+		s.Layout.AddBox(box)
+	}
 	return w
 }
 
-func (s *Sprites) DrawInt(n, xpos, ypos int, isSign, isLeadZero bool) int {
-	sign := s.PLASSPRITE
-	if n < 0 {
-		sign = s.MINUSSPRITE
+// Seed restarts the random placement of clouds, drops and leaves, so
+// the same seed scatters them the same way again.
+func (s *Sprites) Seed(seed int64) {
+	s.rnd.Seed(seed)
+}
+
+// Accent is red on tri-colour panels and black otherwise.
+func (s *Sprites) Accent() color.Color {
+	if s.TRICOLOR {
+		return s.Red
 	}
-	n = abs(n)
+	return s.Black
+}
 
-	n1 := n / 10
-	n2 := n % 10
-	dx := 0
+// recolorKey tells the recoloured versions of a sprite apart, as
+// TRICOLOR and GREYSCALE can change between two drawings.
+type recolorKey struct {
+	img      image.Image
+	tricolor bool
+	shade    bool
+}
+
+// recolor maps a sprite from the pack colours to the output colours with
+// a transparent background, shading clouds grey in GREYSCALE mode.
+func (s *Sprites) recolor(name string, img image.Image) image.Image {
+	shade := s.GREYSCALE && name == s.atlas.Pack.Role("cloud").Sprite
+	key := recolorKey{img, s.TRICOLOR, shade}
+	if out, ok := s.spriteCache[key]; ok {
+		return out
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			switch img.At(x, y) {
+			case s.atlas.Pack.ink:
+				out.Set(x, y, s.Black)
+			case s.atlas.Pack.paper:
+				if shade {
+					out.Set(x, y, color.Gray{uint8(0xF0 - 0x50*(y-b.Min.Y)/b.Dy())})
+				} else {
+					out.Set(x, y, s.White)
+				}
+			case s.atlas.Pack.accent:
+				out.Set(x, y, s.Accent())
+			}
+		}
+	}
+	s.spriteCache[key] = out
+	return out
+}
+
+func (s *Sprites) isOpaque(col color.Color) bool {
+	p := s.atlas.Pack
+	return col == p.ink || col == p.paper || col == p.accent
+}
+
+// DrawRole draws the sprite the pack assigns to a role at the given
+// level, shifted by the role's anchor.
+func (s *Sprites) DrawRole(role string, level, xpos, ypos int) int {
+	r := s.atlas.Pack.Role(role)
+	return s.Draw(r.Sprite, r.Level(level), xpos+r.Anchor[0], ypos+r.Anchor[1])
+}
+
+// DrawRoleIndex is DrawRole for roles indexed by value rather than by
+// level, like digit glyphs.
+func (s *Sprites) DrawRoleIndex(role string, index, xpos, ypos int) int {
+	r := s.atlas.Pack.Role(role)
+	return s.Draw(r.Sprite, index, xpos+r.Anchor[0], ypos+r.Anchor[1])
+}
+
+func (s *Sprites) Anchor(role, name string) image.Point {
+	return s.atlas.Pack.Role(role).AnchorPoint(name)
+}
+
+// drawSnowCap lays a white cap with a black crust on top of the
+// topmost opaque pixel of every sprite column.
+func (s *Sprites) drawSnowCap(img image.Image, xpos, ypos, depth int) {
+	w, h := img.Bounds().Max.X, img.Bounds().Max.Y
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if !s.isOpaque(img.At(x, y)) {
+				continue
+			}
+			for d := 1; d < depth; d++ {
+				s.Dot(xpos+x, ypos+y-d, s.White)
+			}
+			s.Dot(xpos+x, ypos+y-depth, s.Black)
+			break
+		}
+	}
+}
 
+const (
+	ALIGN_LEFT = iota
+	ALIGN_RIGHT
+	ALIGN_CENTER
+)
+
+// GLYPHS maps the characters DrawText understands to digit sprite indexes.
+var GLYPHS = map[rune]int{
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+	'+': 10, '-': 11, ':': 12, '.': 13, '°': 14, 'C': 15, 'F': 16, 'K': 17,
+	'%': 18, 'm': 19, 'h': 20, 'P': 21, 'a': 22, 'i': 23, 'n': 24,
+}
+
+func FormatNumber(v float64, decimals int, isSign bool) string {
+	text := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	if text == strconv.FormatFloat(0, 'f', decimals, 64) {
+		v = 0
+	}
+	if v < 0 {
+		return "-" + text
+	}
 	if isSign {
-		w := s.Draw("digit", sign, xpos+dx, ypos)
-		dx += w + 1
+		return "+" + text
+	}
+	return text
+}
+
+func (s *Sprites) roleWidth(role string, index int) int {
+	img := s.atlas.Get(s.atlas.Pack.Role(role).Sprite, index)
+	if img == nil {
+		return 0
 	}
-	if n1 != 0 || isLeadZero {
-		w := s.Draw("digit", n1, xpos+dx, ypos)
+	return img.Bounds().Max.X
+}
+
+func (s *Sprites) TextHeight() int {
+	img := s.atlas.Get(s.atlas.Pack.Role("digit").Sprite, 0)
+	if img == nil {
+		return 0
+	}
+	return img.Bounds().Max.Y
+}
+
+// TextWidth returns the width DrawText would take for text, one pixel of
+// spacing between glyphs included. Unknown characters are skipped.
+func (s *Sprites) TextWidth(text string) int {
+	w := 0
+	for _, c := range text {
+		index, ok := GLYPHS[c]
+		if !ok {
+			continue
+		}
+		if w > 0 {
+			w++
+		}
+		w += s.roleWidth("digit", index)
+	}
+	return w
+}
+
+// DrawText renders text with the digit sprites, xpos being the left edge,
+// the right edge or the centre depending on align. It returns the
+// rendered width.
+func (s *Sprites) DrawText(text string, xpos, ypos, align int) int {
+	width := s.TextWidth(text)
+	if tc, ok := s.Canvas.(TextCanvas); ok {
+		tc.DrawText(text, xpos, ypos, align, s.Black)
+		return width
+	}
+
+	switch align {
+	case ALIGN_RIGHT:
+		xpos -= width
+	case ALIGN_CENTER:
+		xpos -= width / 2
+	}
+
+	dx := 0
+	for _, c := range text {
+		index, ok := GLYPHS[c]
+		if !ok {
+			continue
+		}
+		w := s.DrawRoleIndex("digit", index, xpos+dx, ypos)
 		dx += w + 1
 	}
-	w := s.Draw("digit", n2, xpos+dx, ypos)
-	dx += w + 1
-	return dx
+	return width
+}
+
+func (s *Sprites) DrawInt(n, xpos, ypos int, isSign, isLeadZero bool) int {
+	text := FormatNumber(float64(n), 0, isSign)
+	if isLeadZero && abs(n) < 10 {
+		text = text[:len(text)-1] + "0" + text[len(text)-1:]
+	}
+	return s.DrawText(text, xpos, ypos, ALIGN_LEFT) + 1
 }
 
 func (s *Sprites) DrawClock(xpos, ypos, h, m int) int {
-	dx := 0
-	w := s.DrawInt(h, xpos+dx, ypos, false, true)
-	dx += w
-	w = s.Draw("digit", 12, xpos+dx, ypos) // Assuming 12 is the index for ':'
-	dx += w
-	dx += s.DrawInt(m, xpos+dx, ypos, false, true)
-	dx++
-	return dx
+	return s.DrawText(fmt.Sprintf("%02d:%02d", h, m), xpos, ypos, ALIGN_LEFT) + 1
 }
 
 func (s *Sprites) DrawCloud(percent, xpos, ypos, width, height int) {
@@ -126,107 +303,337 @@ func (s *Sprites) DrawCloud(percent, xpos, ypos, width, height int) {
 	cloudSet := s.getCloudSet(percent)
 
 	for _, c := range cloudSet {
-		s.Draw("cloud", c, xpos+rand.Intn(width), ypos)
+		s.DrawRole("cloud", c, xpos+s.rnd.Intn(width), ypos)
 	}
 }
 
+// getCloudSet returns the cloud levels for a coverage, from the smallest
+// cloud of the pack's cloud role (level 0) to the largest (level 5).
 func (s *Sprites) getCloudSet(percent int) []int {
 	switch {
 	case percent < 5:
-		return []int{2}
+		return []int{0}
 	case percent < 10:
-		return []int{3, 2}
+		return []int{1, 0}
 	case percent < 20:
-		return []int{5, 3, 2}
+		return []int{2, 1, 0}
 	case percent < 30:
-		return []int{10, 5}
+		return []int{3, 2}
 	case percent < 40:
-		return []int{10, 10}
+		return []int{3, 3}
 	case percent < 50:
-		return []int{10, 10, 5}
+		return []int{3, 3, 2}
 	case percent < 60:
-		return []int{30, 5}
+		return []int{4, 2}
 	case percent < 70:
-		return []int{30, 10}
+		return []int{4, 3}
 	case percent < 80:
-		return []int{30, 10, 5, 5}
+		return []int{4, 3, 2, 2}
 	case percent < 90:
-		return []int{30, 10, 10}
+		return []int{4, 3, 3}
 	default:
-		return []int{50, 30, 10, 10, 5}
+		return []int{5, 4, 3, 3, 2}
 	}
 }
 
-func (s *Sprites) DrawRain(value, xpos, ypos, width int, tline []int) {
+// DrawRain draws dense solid streaks for likely rain and sparse dashes
+// when the probability of precipitation is below POPCERTAIN.
+func (s *Sprites) DrawRain(value, pop float64, xpos, ypos, width int, tline []int) {
 	ypos++
-	r := 1.0 - (float64(value)/5.0)/20.0 // HEAVYRAIN and RAINFACTOR
+	r := 1.0 - (value/5.0)/20.0*pop // HEAVYRAIN and RAINFACTOR
+	certain := pop >= s.POPCERTAIN
+	ystep := 2
+	if !certain {
+		ystep = 4
+	}
 
 	for x := xpos; x < xpos+width; x++ {
-		for y := ypos; y < tline[x]; y += 2 {
+		if !certain && x%2 != 0 {
+			continue
+		}
+		for y := ypos; y < tline[x]; y += ystep {
 			if x >= s.w || y >= s.h {
 				continue
 			}
-			if rand.Float64() > r {
-				s.img.Set(x, y, s.Black)
-				s.img.Set(x, y-1, s.Black)
+			if s.rnd.Float64() > r {
+				s.Dot(x, y, s.Black)
+				if certain {
+					s.Dot(x, y-1, s.Black)
+				}
 			}
 		}
 	}
 }
 
-func (s *Sprites) DrawSnow(value, xpos, ypos, width int, tline []int) {
+func (s *Sprites) DrawSnow(value, pop float64, xpos, ypos, width int, tline []int) {
 	ypos++
-	r := 1.0 - (float64(value)/5.0)/10.0 // HEAVYSNOW and SNOWFACTOR
+	r := 1.0 - (value/5.0)/10.0*pop // HEAVYSNOW and SNOWFACTOR
 
 	for x := xpos; x < xpos+width; x++ {
 		for y := ypos; y < tline[x]; y += 2 {
 			if x >= s.w || y >= s.h {
 				continue
 			}
-			if rand.Float64() > r {
-				s.img.Set(x, y, s.Black)
+			if s.rnd.Float64() > r {
+				s.Dot(x, y, s.Black)
 			}
 		}
 	}
 }
 
-func abs(n int) int {
-	if n < 0 {
-		return -n
+// DrawSleet alternates slanted rain ticks with snow pellets so mixed
+// precipitation reads differently from either rain or snow alone.
+func (s *Sprites) DrawSleet(value, pop float64, xpos, ypos, width int, tline []int) {
+	ypos++
+	r := 1.0 - (value/5.0)/15.0*pop // HEAVYRAIN and between RAINFACTOR and SNOWFACTOR
+
+	for x := xpos; x < xpos+width; x++ {
+		for y := ypos; y < tline[x]; y += 3 {
+			if x >= s.w || y >= s.h {
+				continue
+			}
+			if s.rnd.Float64() > r {
+				if (x+y)%2 == 0 {
+					s.Dot(x, y, s.Black)
+					s.Dot(x+1, y-1, s.Black)
+				} else {
+					s.Dot(x, y, s.Black)
+				}
+			}
+		}
 	}
-	return n
 }
 
-func formatIndex(index int) string {
-	return fmt.Sprintf("%02d", index)
+// DrawIce glazes the ground line under freezing rain with a black band
+// and white glints.
+func (s *Sprites) DrawIce(iline []bool, tline []int, xpos, width int) {
+	for x := xpos; x < xpos+width; x++ {
+		if x >= len(iline) || x >= len(tline) || !iline[x] {
+			continue
+		}
+		s.Dot(x, tline[x]+1, s.Accent())
+		if x%4 == 0 {
+			s.Dot(x, tline[x]+2, s.White)
+		} else {
+			s.Dot(x, tline[x]+2, s.Accent())
+		}
+		s.Dot(x, tline[x]+3, s.Accent())
+	}
 }
 
-func loadImage(filename string) (image.Image, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// DrawFuzz scatters dots above and below the temperature line, thinning
+// out with the distance, where pline says how far.
+func (s *Sprites) DrawFuzz(pline, tline []int, xpos, width int) {
+	for x := xpos; x < xpos+width; x++ {
+		n := pline[x]
+		for d := 1; d <= n; d++ {
+			p := 0.6 * float64(n-d+1) / float64(n+1)
+			if s.rnd.Float64() < p {
+				s.Dot(x, tline[x]-d, s.Black)
+			}
+			if s.rnd.Float64() < p {
+				s.Dot(x, tline[x]+d, s.Black)
+			}
+		}
 	}
-	defer file.Close()
-	return png.Decode(file)
 }
 
-func main() {
-	canvasFile := "../test.bmp"
-	spritesDir := "../sprite"
+func (s *Sprites) DrawSnowCover(sline, tline []int, xpos, width int) {
+	for x := xpos; x < xpos+width; x++ {
+		if x >= len(sline) || x >= len(tline) || sline[x] <= 0 {
+			continue
+		}
+		for d := 1; d < sline[x]; d++ {
+			s.Dot(x, tline[x]-d, s.White)
+		}
+		s.Dot(x, tline[x]-sline[x], s.Black)
+	}
+}
 
-	canvas, err := loadImage(canvasFile)
-	if err != nil {
-		panic(err)
+func windDegDist(deg1, deg2 float64) float64 {
+	d := math.Abs(math.Mod(deg1, 360) - math.Mod(deg2, 360))
+	if d > 180 {
+		d = 360 - d
 	}
+	return d
+}
 
-	s := NewSprites(spritesDir, canvas)
-	s.Draw("house", 0, 100, 100)
+// windDirSprites adds more trees of a family the closer the wind
+// direction is to the family's own direction, so intermediate
+// directions are drawn as a mix of two families.
+func windDirSprites(deg, deg0 float64, name string, list []string) []string {
+	count := []int{4, 3, 3, 2, 2, 1, 1}
+	step := 11.25
+	n := int(windDegDist(deg, deg0) / step)
+	if n < len(count) {
+		for i := 0; i < count[n]; i++ {
+			list = append(list, name)
+		}
+	}
+	return list
+}
 
-	outputFile, err := os.Create("../tmp/sprites_test.bmp")
-	if err != nil {
-		panic(err)
+func Beaufort(speed float64) int {
+	limits := []float64{0.5, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7}
+	for i, l := range limits {
+		if speed < l {
+			return i
+		}
+	}
+	return len(limits)
+}
+
+// getWindSet returns the sprite indexes of a tree group for a wind speed
+// in m/s, with the limits of sprites.py: the strongest trees from
+// 12.4 m/s on.
+func (s *Sprites) getWindSet(speed float64) []int {
+	switch {
+	case speed <= 0.4:
+		return []int{}
+	case speed <= 0.7:
+		return []int{0}
+	case speed <= 1.7:
+		return []int{1, 0, 0}
+	case speed <= 3.3:
+		return []int{1, 1, 0, 0}
+	case speed <= 5.2:
+		return []int{1, 2, 0, 0}
+	case speed <= 7.4:
+		return []int{1, 2, 2, 0}
+	case speed <= 9.8:
+		return []int{1, 2, 3, 0}
+	case speed <= 12.4:
+		return []int{2, 2, 3, 0}
+	default:
+		return []int{3, 3, 3, 3}
 	}
-	defer outputFile.Close()
+}
+
+// DrawWind plants a group of trees on tline: pine, east, palm and tree
+// sprites stand for N, E, S and W wind, the sprite index for the
+// strength. Gusts well above the sustained wind bend one of
+// the trees over to the gust strength.
+func (s *Sprites) DrawWind(speed, gust, deg float64, xpos int, tline []int) {
+	list := []string{}
+	list = windDirSprites(deg, 0, "wind.N", list)
+	list = windDirSprites(deg, 90, "wind.E", list)
+	list = windDirSprites(deg, 180, "wind.S", list)
+	list = windDirSprites(deg, 270, "wind.W", list)
+	s.rnd.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
 
-	png.Encode(outputFile, s.img)
+	windIndex := s.getWindSet(speed)
+	if gust-speed >= s.GUSTDELTA {
+		gustIndex := 0
+		for _, i := range s.getWindSet(gust) {
+			if i > gustIndex {
+				gustIndex = i
+			}
+		}
+		if len(windIndex) == 0 {
+			windIndex = []int{gustIndex}
+		} else {
+			windIndex[0] = gustIndex
+		}
+	}
+	s.rnd.Shuffle(len(windIndex), func(i, j int) { windIndex[i], windIndex[j] = windIndex[j], windIndex[i] })
+
+	if len(list) == 0 {
+		return
+	}
+	ix := xpos
+	for j, i := range windIndex {
+		offset := ix + 5
+		if offset >= len(tline) {
+			break
+		}
+		s.DrawRole(list[j%len(list)], i, ix, tline[offset]+1)
+		ix += 9
+	}
+}
+
+// DrawSmoke draws a chimney plume at xpos, ypos for the pressure the
+// forecast expects: rising pressure (trend > 1) sends the smoke straight
+// up, steady pressure lets it drift, falling pressure (trend < -1)
+// pushes it down along the roof.
+func (s *Sprites) DrawSmoke(trend float64, xpos, ypos int) {
+	for i := 1; i <= 6; i++ {
+		var dx, dy int
+		switch {
+		case trend > 1:
+			dx, dy = i/3, -2*i
+		case trend < -1:
+			dx, dy = 2*i, -i+i*i/4
+		default:
+			dx, dy = i, -i
+		}
+		x, y := xpos+dx, ypos+dy
+		if i%2 == 0 {
+			s.Dot(x, y, s.Black)
+			s.Dot(x+1, y, s.Black)
+			s.Dot(x, y-1, s.Black)
+			s.Dot(x+1, y-1, s.Black)
+		} else {
+			s.Dot(x, y, s.Black)
+		}
+	}
+}
+
+func (s *Sprites) DrawHaze(xpos, width int, tline []int) {
+	for x := xpos; x < xpos+width && x < len(tline); x++ {
+		if x%3 == 0 {
+			s.Dot(x, tline[x]-4, s.Black)
+		}
+		if x%3 == 1 {
+			s.Dot(x, tline[x]-7, s.Black)
+		}
+	}
+}
+
+func (s *Sprites) DrawCracks(xpos, width int, tline []int) {
+	for x := xpos; x < xpos+width && x+1 < len(tline); x++ {
+		if x%6 == 0 {
+			s.Dot(x, tline[x]+1, s.Black)
+			s.Dot(x+1, tline[x+1]+2, s.Black)
+		}
+	}
+}
+
+// DrawDew hangs dew drops next to a flower head standing on ypos.
+func (s *Sprites) DrawDew(xpos, ypos int) {
+	s.Dot(xpos+9, ypos-12, s.Black)
+	s.Dot(xpos+9, ypos-11, s.Black)
+	s.Dot(xpos-1, ypos-9, s.Black)
+	s.Dot(xpos-1, ypos-8, s.Black)
+}
+
+// DrawGust scatters leaves flying downwind of a tree group when gusts
+// exceed the sustained wind by more than GUSTDELTA.
+func (s *Sprites) DrawGust(speed, gust float64, xpos int, tline []int) {
+	d := gust - speed
+	if d < s.GUSTDELTA {
+		return
+	}
+
+	n := int(d / 2.5)
+	for i := 0; i < n; i++ {
+		x := xpos + s.rnd.Intn(36)
+		if x >= len(tline) {
+			continue
+		}
+		y := tline[x] - 10 - s.rnd.Intn(16)
+		s.Dot(x, y, s.Black)
+		s.Dot(x+1, y, s.Black)
+		s.Dot(x+1, y-1, s.Black)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func formatIndex(index int) string {
+	return fmt.Sprintf("%02d", index)
 }
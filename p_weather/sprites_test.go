@@ -0,0 +1,311 @@
+package p_weather
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// newTestSprites draws on a blank panel-sized canvas with the embedded
+// sprite pack.
+func newTestSprites() *Sprites {
+	s, err := NewSprites("", image.NewRGBA(image.Rect(0, 0, 296, 128)))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestGetWindSet(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  []int
+	}{
+		{0, []int{}},
+		{0.4, []int{}},
+		{0.5, []int{0}},
+		{1.7, []int{1, 0, 0}},
+		{3, []int{1, 1, 0, 0}},
+		{5.2, []int{1, 2, 0, 0}},
+		{7, []int{1, 2, 2, 0}},
+		{9.8, []int{1, 2, 3, 0}},
+		{12.4, []int{2, 2, 3, 0}},
+		{12.5, []int{3, 3, 3, 3}},
+		{30, []int{3, 3, 3, 3}},
+	}
+	var s *Sprites
+	for _, tt := range tests {
+		if got := s.getWindSet(tt.speed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getWindSet(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		v        float64
+		decimals int
+		isSign   bool
+		want     string
+	}{
+		{5, 0, false, "5"},
+		{5, 0, true, "+5"},
+		{-5, 0, true, "-5"},
+		{102, 0, true, "+102"},
+		{1013, 0, false, "1013"},
+		{2.5, 1, false, "2.5"},
+		{-12.34, 1, false, "-12.3"},
+		{-0.4, 0, false, "0"},
+		{-0.4, 0, true, "+0"},
+		{-0.04, 1, true, "+0.0"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.v, tt.decimals, tt.isSign); got != tt.want {
+			t.Errorf("FormatNumber(%v, %d, %v) = %q, want %q", tt.v, tt.decimals, tt.isSign, got, tt.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"7", 3},
+		{"102", 11},
+		{"-2.5", 13},
+		{"12:30", 19},
+		{"+5°C", 15},
+		{"0.1in", 15},
+		{"x5", 3}, // unknown characters take no space
+	}
+	s := newTestSprites()
+	for _, tt := range tests {
+		if got := s.TextWidth(tt.text); got != tt.want {
+			t.Errorf("TextWidth(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDrawTextAlign(t *testing.T) {
+	tests := []struct {
+		align int
+		left  int
+	}{
+		{ALIGN_LEFT, 20},
+		{ALIGN_RIGHT, 9},
+		{ALIGN_CENTER, 15},
+	}
+	for _, tt := range tests {
+		s := newTestSprites()
+		img := s.Canvas.(*RasterCanvas).Image()
+		if w := s.DrawText("808", 20, 10, tt.align); w != 11 {
+			t.Errorf("align %d: DrawText width = %d, want 11", tt.align, w)
+		}
+		left := -1
+		for x := 0; x < 296 && left < 0; x++ {
+			for y := 0; y < 128; y++ {
+				if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+					left = x
+					break
+				}
+			}
+		}
+		if left != tt.left {
+			t.Errorf("align %d: text starts at x=%d, want %d", tt.align, left, tt.left)
+		}
+	}
+}
+
+func TestGetCloudSet(t *testing.T) {
+	tests := []struct {
+		percent int
+		want    []int // sprite indexes of the embedded pack
+		small   []int // sprite indexes of a pack with two clouds
+	}{
+		{2, []int{2}, []int{7}},
+		{7, []int{3, 2}, []int{8, 7}},
+		{15, []int{5, 3, 2}, []int{8, 8, 7}},
+		{25, []int{10, 5}, []int{8, 8}},
+		{55, []int{30, 5}, []int{8, 8}},
+		{75, []int{30, 10, 5, 5}, []int{8, 8, 8, 8}},
+		{100, []int{50, 30, 10, 10, 5}, []int{8, 8, 8, 8, 8}},
+	}
+	s := newTestSprites()
+	cloud := s.atlas.Pack.Role("cloud")
+	small := &SpriteRole{Sprite: "cloud", Index: []int{7, 8}}
+	for _, tt := range tests {
+		got, gotSmall := []int{}, []int{}
+		for _, level := range s.getCloudSet(tt.percent) {
+			got = append(got, cloud.Level(level))
+			gotSmall = append(gotSmall, small.Level(level))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getCloudSet(%d) draws %v, want %v", tt.percent, got, tt.want)
+		}
+		if !reflect.DeepEqual(gotSmall, tt.small) {
+			t.Errorf("getCloudSet(%d) with two clouds draws %v, want %v", tt.percent, gotSmall, tt.small)
+		}
+	}
+}
+
+func TestRecolorModes(t *testing.T) {
+	// an accent pixel over a paper pixel, in the pack's colours
+	img := image.NewRGBA(image.Rect(0, 0, 1, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(0, 1, color.RGBA{255, 255, 255, 255})
+
+	tests := []struct {
+		name      string
+		tricolor  bool
+		greyscale bool
+		accent    color.RGBA
+		paper     color.RGBA
+	}{
+		{"black and white", false, false, color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+		{"tricolor", true, false, color.RGBA{255, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+		{"greyscale", false, true, color.RGBA{0, 0, 0, 255}, color.RGBA{0xC8, 0xC8, 0xC8, 255}},
+		{"black and white again", false, false, color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+	}
+	// one Sprites for all modes, so a stale cache entry would show
+	s := newTestSprites()
+	for _, tt := range tests {
+		s.TRICOLOR, s.GREYSCALE = tt.tricolor, tt.greyscale
+		out := s.recolor("cloud", img)
+		if got := color.RGBAModel.Convert(out.At(0, 0)); got != tt.accent {
+			t.Errorf("%s: accent drawn as %v, want %v", tt.name, got, tt.accent)
+		}
+		if got := color.RGBAModel.Convert(out.At(0, 1)); got != tt.paper {
+			t.Errorf("%s: paper drawn as %v, want %v", tt.name, got, tt.paper)
+		}
+	}
+}
+
+// inkCount counts the pixels drawn black on the sprites' canvas.
+func inkCount(s *Sprites) int {
+	img := s.Canvas.(*RasterCanvas).Image()
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] == 255 && img.Pix[i] < 0x80 {
+			n++
+		}
+	}
+	return n
+}
+
+// flatLine is a ground line at y across the panel.
+func flatLine(y int) []int {
+	tline := make([]int, 296)
+	for x := range tline {
+		tline[x] = y
+	}
+	return tline
+}
+
+func TestDrawPrecipitationPop(t *testing.T) {
+	draws := []struct {
+		name string
+		draw func(s *Sprites, pop float64)
+	}{
+		{"rain", func(s *Sprites, pop float64) { s.DrawRain(3, pop, 0, 10, 60, flatLine(100)) }},
+		{"snow", func(s *Sprites, pop float64) { s.DrawSnow(3, pop, 0, 10, 60, flatLine(100)) }},
+		{"sleet", func(s *Sprites, pop float64) { s.DrawSleet(3, pop, 0, 10, 60, flatLine(100)) }},
+	}
+	for _, d := range draws {
+		last := -1
+		for _, pop := range []float64{0, 0.2, 0.5, 1} {
+			s := newTestSprites()
+			s.Seed(1)
+			d.draw(s, pop)
+			n := inkCount(s)
+			if pop == 0 && n != 0 {
+				t.Errorf("%s: %d pixels drawn at pop 0", d.name, n)
+			}
+			if n <= last && pop > 0 {
+				t.Errorf("%s: pop %v draws %d pixels, no more than the %d of a lower pop", d.name, pop, n, last)
+			}
+			last = n
+		}
+	}
+}
+
+func TestDrawGust(t *testing.T) {
+	tests := []struct {
+		name        string
+		speed, gust float64
+		leaves      bool
+	}{
+		{"steady wind", 5, 5, false},
+		{"gust below GUSTDELTA", 5, 9.9, false},
+		{"gust at GUSTDELTA", 5, 10, true},
+		{"strong gusts", 5, 20, true},
+		{"calm with gusts", 0, 12, true},
+	}
+	for _, tt := range tests {
+		s := newTestSprites()
+		s.Seed(1)
+		s.DrawGust(tt.speed, tt.gust, 100, flatLine(100))
+		if got := inkCount(s) > 0; got != tt.leaves {
+			t.Errorf("%s: leaves drawn %v, want %v", tt.name, got, tt.leaves)
+		}
+	}
+}
+
+func TestDrawWindGust(t *testing.T) {
+	tests := []struct {
+		name        string
+		speed, gust float64
+		trees       bool
+	}{
+		{"calm", 0.4, 0.4, false},
+		{"calm with a light gust", 0.4, 5, false},
+		{"calm with a strong gust", 0.4, 15, true},
+		{"breeze", 3, 3, true},
+	}
+	for _, tt := range tests {
+		s := newTestSprites()
+		s.Seed(1)
+		s.DrawWind(tt.speed, tt.gust, 0, 100, flatLine(100))
+		if got := inkCount(s) > 0; got != tt.trees {
+			t.Errorf("%s: trees drawn %v, want %v", tt.name, got, tt.trees)
+		}
+	}
+
+	// a gust bends one tree of the group over to the gust strength
+	steady, gusty := newTestSprites(), newTestSprites()
+	steady.Seed(1)
+	gusty.Seed(1)
+	steady.DrawWind(3, 3, 0, 100, flatLine(100))
+	gusty.DrawWind(3, 20, 0, 100, flatLine(100))
+	if reflect.DeepEqual(steady.Canvas.(*RasterCanvas).Image().Pix, gusty.Canvas.(*RasterCanvas).Image().Pix) {
+		t.Errorf("gusts of 20 m/s draw the same trees as a steady 3 m/s")
+	}
+}
+
+func TestDrawSmoke(t *testing.T) {
+	const x, y = 100, 60
+	tests := []struct {
+		name  string
+		trend float64 // in PRESSURE_TREND_HPA
+		top   image.Point
+	}{
+		{"rising", 2, image.Pt(x+2, y-12)},
+		{"steady", 0, image.Pt(x+6, y-6)},
+		{"slowly rising", 1, image.Pt(x+6, y-6)},
+		{"slowly falling", -1, image.Pt(x+6, y-6)},
+		{"falling", -2, image.Pt(x+12, y+3)},
+	}
+	for _, tt := range tests {
+		s := newTestSprites()
+		s.DrawSmoke(tt.trend, x, y)
+		img := s.Canvas.(*RasterCanvas).Image()
+		for _, other := range tests {
+			puff := color.RGBAModel.Convert(img.At(other.top.X, other.top.Y)) == s.Black
+			if want := other.top == tt.top; puff != want {
+				t.Errorf("%s: puff at %v is %v, want %v", tt.name, other.top, puff, want)
+			}
+		}
+	}
+}
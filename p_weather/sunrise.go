@@ -1,7 +1,6 @@
-package main
+package p_weather
 
 import (
-	"fmt"
 	"math"
 	"time"
 )
@@ -106,12 +105,3 @@ func degToRad(deg float64) float64 {
 func radToDeg(rad float64) float64 {
 	return rad * 180 / math.Pi
 }
-
-func main() {
-	s := NewSun(50.4546600, 30.5238000) // Default Kyiv
-	now := time.Now()
-
-	fmt.Println("Sunrise:", s.Sunrise(now))
-	fmt.Println("Sunset:", s.Sunset(now))
-	fmt.Println("Solar Noon:", s.SolarNoon(now))
-}
@@ -0,0 +1,127 @@
+package p_weather
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SVGCanvas collects the landscape as vector elements: sprites as
+// embedded PNG images, single pixels as unit squares merged into one path
+// per colour, curves as Bézier paths and labels as real text.
+type SVGCanvas struct {
+	FONTSIZE int
+	w, h     int
+	elems    []string
+	dots     map[string]*strings.Builder
+	sprites  map[image.Image]string
+}
+
+func NewSVGCanvas(w, h int) *SVGCanvas {
+	return &SVGCanvas{
+		FONTSIZE: 7,
+		w:        w,
+		h:        h,
+		dots:     map[string]*strings.Builder{},
+		sprites:  map[image.Image]string{},
+	}
+}
+
+func svgColor(col color.Color) string {
+	r, g, b, _ := col.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+func (c *SVGCanvas) Bounds() image.Rectangle {
+	return image.Rect(0, 0, c.w, c.h)
+}
+
+func (c *SVGCanvas) SetPixel(x, y int, col color.Color) {
+	fill := svgColor(col)
+	d, ok := c.dots[fill]
+	if !ok {
+		d = &strings.Builder{}
+		c.dots[fill] = d
+	}
+	fmt.Fprintf(d, "M%d %dh1v1h-1z", x, y)
+}
+
+// flush turns the dots collected so far into paths, keeping them below
+// the elements added after them.
+func (c *SVGCanvas) flush() {
+	fills := []string{}
+	for fill := range c.dots {
+		fills = append(fills, fill)
+	}
+	sort.Strings(fills)
+	for _, fill := range fills {
+		c.elems = append(c.elems, fmt.Sprintf(`<path d="%s" fill="%s"/>`, c.dots[fill].String(), fill))
+	}
+	c.dots = map[string]*strings.Builder{}
+}
+
+// BlitSprite embeds a sprite with its top left corner at x, y. The sprite
+// is encoded once and referenced by every later use.
+func (c *SVGCanvas) BlitSprite(img image.Image, x, y int) {
+	c.flush()
+	id, ok := c.sprites[img]
+	if !ok {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return
+		}
+		id = fmt.Sprintf("sprite%d", len(c.sprites))
+		c.sprites[img] = id
+		b := img.Bounds()
+		c.elems = append(c.elems, fmt.Sprintf(
+			`<defs><image id="%s" width="%d" height="%d" style="image-rendering:pixelated" href="data:image/png;base64,%s"/></defs>`,
+			id, b.Dx(), b.Dy(), base64.StdEncoding.EncodeToString(buf.Bytes())))
+	}
+	c.elems = append(c.elems, fmt.Sprintf(`<use href="#%s" x="%d" y="%d"/>`, id, x, y))
+}
+
+// DrawPath strokes through the pixel centres, the way the raster
+// canvases plot the path.
+func (c *SVGCanvas) DrawPath(p *Path, col color.Color, dashed bool) {
+	c.flush()
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="2 2"`
+	}
+	c.elems = append(c.elems, fmt.Sprintf(`<path d="%s" fill="none" stroke="%s" stroke-width="1" transform="translate(0.5 0.5)"%s/>`,
+		p.String(), svgColor(col), dash))
+}
+
+// DrawText places a label with its baseline at y, anchored like
+// Sprites.DrawText.
+func (c *SVGCanvas) DrawText(text string, x, y, align int, col color.Color) {
+	c.flush()
+	anchor := "start"
+	switch align {
+	case ALIGN_RIGHT:
+		anchor = "end"
+	case ALIGN_CENTER:
+		anchor = "middle"
+	}
+	c.elems = append(c.elems, fmt.Sprintf(`<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="%s" fill="%s">%s</text>`,
+		x, y, c.FONTSIZE, anchor, svgColor(col), html.EscapeString(text)))
+}
+
+func (c *SVGCanvas) WriteTo(w io.Writer) (int64, error) {
+	c.flush()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", c.w, c.h, c.w, c.h)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", c.w, c.h)
+	for _, e := range c.elems {
+		buf.WriteString(e + "\n")
+	}
+	buf.WriteString("</svg>\n")
+	return buf.WriteTo(w)
+}
@@ -0,0 +1,108 @@
+package p_weather
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// svgElements parses an SVG document and counts its elements by name,
+// collecting the text of the text elements.
+func svgElements(t *testing.T, c *SVGCanvas) (map[string]int, []string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	texts := []string{}
+	inText := false
+	dec := xml.NewDecoder(&buf)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG does not parse: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			counts[tok.Name.Local]++
+			inText = tok.Name.Local == "text"
+			if tok.Name.Local == "svg" && tok.Name.Space != "http://www.w3.org/2000/svg" {
+				t.Errorf("svg element in namespace %q", tok.Name.Space)
+			}
+		case xml.CharData:
+			if inText {
+				texts = append(texts, string(tok))
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+	return counts, texts
+}
+
+func TestSVGCanvas(t *testing.T) {
+	c := NewSVGCanvas(40, 20)
+	c.SetPixel(1, 1, color.Black)
+	c.SetPixel(2, 1, color.Black)
+	c.SetPixel(3, 1, color.RGBA{255, 0, 0, 255})
+	sprite := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	c.BlitSprite(sprite, 5, 5)
+	c.BlitSprite(sprite, 15, 5)
+	p := &Path{}
+	p.MoveTo(0, 10)
+	p.CurveTo(3, 10, 6, 15, 9, 15)
+	c.DrawPath(p, color.Black, true)
+	c.DrawText("-3°C <&>", 20, 18, ALIGN_CENTER, color.Black)
+
+	counts, texts := svgElements(t, c)
+	want := map[string]int{"svg": 1, "rect": 1, "path": 3, "defs": 1, "image": 1, "use": 2, "text": 1}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("%d <%s> elements, want %d", counts[name], name, n)
+		}
+	}
+	if len(texts) != 1 || texts[0] != "-3°C <&>" {
+		t.Errorf("texts %q", texts)
+	}
+}
+
+func TestSVGScene(t *testing.T) {
+	start := time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)
+	ws := &WeatherSeries{Latitude: 52.2, Longitude: 21.0}
+	for h := 0; h <= 48; h += FORECAST_PERIOD_HOURS {
+		ws.F = append(ws.F, &WeatherInfo{T: start.Add(time.Duration(h) * time.Hour), ID: 500, Clouds: 80, Rain: 2, Pop: 1,
+			Temp: float64(h % 10), FeelsLike: float64(h % 10), Pressure: 1013, Humidity: 50, Windspeed: 4})
+	}
+	template := image.NewRGBA(image.Rect(0, 0, 296, 128))
+	spr := newTestSprites()
+	c := NewSVGCanvas(296, 128)
+	spr.Canvas = c
+	dw := NewDrawWeather(template, spr)
+	dw.VERBOSE = false
+	dw.DrawAt(65, ws, start)
+
+	counts, texts := svgElements(t, c)
+	for _, name := range []string{"path", "image", "use", "text"} {
+		if counts[name] == 0 {
+			t.Errorf("no <%s> in the landscape", name)
+		}
+	}
+	found := false
+	for _, text := range texts {
+		if strings.HasSuffix(text, "°C") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no temperature label with its unit in %q", texts)
+	}
+}
@@ -0,0 +1,37 @@
+package p_weather
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"time"
+)
+
+// TimeLapse renders one frame per hour for the given number of hours,
+// starting at start, and returns them as an endlessly looping GIF with
+// delay hundredths of a second per frame. The frames are drawn quietly,
+// with the clouds drifting from frame to frame.
+func (dw *DrawWeather) TimeLapse(ypos int, ws *WeatherSeries, start time.Time, hours, delay int) *gif.GIF {
+	canvas, verbose := dw.sprite.Canvas, dw.VERBOSE
+	defer func() { dw.sprite.Canvas, dw.VERBOSE, dw.drift = canvas, verbose, false }()
+	dw.VERBOSE, dw.drift = false, true
+
+	pal := color.Palette{dw.sprite.White, dw.sprite.Black, dw.sprite.Red}
+	for g := 1; g < 15; g++ {
+		pal = append(pal, color.Gray{uint8(g * 0x11)})
+	}
+
+	anim := &gif.GIF{}
+	for h := 0; h < hours; h++ {
+		raster := NewRasterCanvas(dw.img)
+		dw.sprite.Canvas = raster
+		dw.DrawAt(ypos, ws, start.Add(time.Duration(h)*time.Hour))
+
+		frame := image.NewPaletted(raster.Bounds(), pal)
+		draw.Draw(frame, frame.Bounds(), raster.Image(), raster.Bounds().Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return anim
+}
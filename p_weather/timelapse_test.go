@@ -0,0 +1,49 @@
+package p_weather
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestTimeLapse(t *testing.T) {
+	start := time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)
+	ws := &WeatherSeries{Latitude: 52.2, Longitude: 21.0}
+	for h := 0; h <= 48; h += FORECAST_PERIOD_HOURS {
+		ws.F = append(ws.F, &WeatherInfo{T: start.Add(time.Duration(h) * time.Hour), ID: 800, Pop: 1,
+			Temp: float64(h % 10), FeelsLike: float64(h % 10), Pressure: 1013, Humidity: 50})
+	}
+
+	tests := []struct {
+		hours, delay int
+	}{
+		{0, 50},
+		{1, 50},
+		{6, 20},
+	}
+	for _, tt := range tests {
+		template := image.NewRGBA(image.Rect(0, 0, 296, 128))
+		spr, err := NewSprites("", template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dw := NewDrawWeather(template, spr)
+		canvas := spr.Canvas
+
+		anim := dw.TimeLapse(65, ws, start, tt.hours, tt.delay)
+		if len(anim.Image) != tt.hours || len(anim.Delay) != tt.hours {
+			t.Errorf("%d hours: %d frames and %d delays", tt.hours, len(anim.Image), len(anim.Delay))
+		}
+		for i, frame := range anim.Image {
+			if frame.Bounds() != template.Bounds() {
+				t.Errorf("%d hours: frame %d is %v", tt.hours, i, frame.Bounds())
+			}
+			if anim.Delay[i] != tt.delay {
+				t.Errorf("%d hours: frame %d delay %d, want %d", tt.hours, i, anim.Delay[i], tt.delay)
+			}
+		}
+		if spr.Canvas != canvas || !dw.VERBOSE || dw.drift {
+			t.Errorf("%d hours: canvas, VERBOSE or drift not restored", tt.hours)
+		}
+	}
+}
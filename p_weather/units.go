@@ -0,0 +1,117 @@
+package p_weather
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Units int
+
+const (
+	UNITS_METRIC Units = iota
+	UNITS_IMPERIAL
+	UNITS_SI
+)
+
+func ParseUnits(name string) (Units, error) {
+	switch strings.ToLower(name) {
+	case "", "metric":
+		return UNITS_METRIC, nil
+	case "imperial":
+		return UNITS_IMPERIAL, nil
+	case "si", "standard":
+		return UNITS_SI, nil
+	}
+	return UNITS_METRIC, fmt.Errorf("unknown units '%s'", name)
+}
+
+func (u Units) String() string {
+	switch u {
+	case UNITS_IMPERIAL:
+		return "imperial"
+	case UNITS_SI:
+		return "si"
+	}
+	return "metric"
+}
+
+// FromCelsius converts a temperature to the units' scale: Fahrenheit for
+// imperial, Kelvin for SI.
+func (u Units) FromCelsius(t float64) float64 {
+	switch u {
+	case UNITS_IMPERIAL:
+		return t*9/5 + 32
+	case UNITS_SI:
+		return t + KTOC
+	}
+	return t
+}
+
+func (u Units) Celsius(t float64) float64 {
+	switch u {
+	case UNITS_IMPERIAL:
+		return (t - 32) * 5 / 9
+	case UNITS_SI:
+		return t - KTOC
+	}
+	return t
+}
+
+// FromMetresPerSecond converts a wind speed to miles per hour for
+// imperial units.
+func (u Units) FromMetresPerSecond(v float64) float64 {
+	if u == UNITS_IMPERIAL {
+		return v / 0.44704
+	}
+	return v
+}
+
+func (u Units) MetresPerSecond(v float64) float64 {
+	if u == UNITS_IMPERIAL {
+		return v * 0.44704
+	}
+	return v
+}
+
+// FromMillimetres converts rain and snow to inches for imperial units.
+func (u Units) FromMillimetres(v float64) float64 {
+	if u == UNITS_IMPERIAL {
+		return v / 25.4
+	}
+	return v
+}
+
+func (u Units) Millimetres(v float64) float64 {
+	if u == UNITS_IMPERIAL {
+		return v * 25.4
+	}
+	return v
+}
+
+// TemperatureUnit and PrecipitationUnit are the labels DrawText can
+// render with the digit glyphs.
+func (u Units) TemperatureUnit() string {
+	switch u {
+	case UNITS_IMPERIAL:
+		return "°F"
+	case UNITS_SI:
+		return "K"
+	}
+	return "°C"
+}
+
+func (u Units) PrecipitationUnit() string {
+	if u == UNITS_IMPERIAL {
+		return "in"
+	}
+	return "mm"
+}
+
+// DegreeScale is the size of one degree of the units relative to a
+// degree Celsius.
+func (u Units) DegreeScale() float64 {
+	if u == UNITS_IMPERIAL {
+		return 9.0 / 5.0
+	}
+	return 1.0
+}
@@ -0,0 +1,79 @@
+package p_weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Units
+		wantErr bool
+	}{
+		{"", UNITS_METRIC, false},
+		{"metric", UNITS_METRIC, false},
+		{"Imperial", UNITS_IMPERIAL, false},
+		{"si", UNITS_SI, false},
+		{"standard", UNITS_SI, false},
+		{"kelvin", UNITS_METRIC, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseUnits(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUnitsConversions(t *testing.T) {
+	tests := []struct {
+		units                Units
+		celsius, temp        float64
+		ms, speed            float64
+		mm, precip           float64
+		tempUnit, precipUnit string
+	}{
+		{UNITS_METRIC, 20, 20, 10, 10, 25.4, 25.4, "°C", "mm"},
+		{UNITS_IMPERIAL, 20, 68, 10, 22.37, 25.4, 1, "°F", "in"},
+		{UNITS_IMPERIAL, -40, -40, 0, 0, 0, 0, "°F", "in"},
+		{UNITS_SI, 20, 293.15, 10, 10, 25.4, 25.4, "K", "mm"},
+	}
+	for _, tt := range tests {
+		u := tt.units
+		if got := u.FromCelsius(tt.celsius); math.Abs(got-tt.temp) > 0.01 {
+			t.Errorf("%v: FromCelsius(%v) = %.2f, want %.2f", u, tt.celsius, got, tt.temp)
+		}
+		if got := u.Celsius(tt.temp); math.Abs(got-tt.celsius) > 0.01 {
+			t.Errorf("%v: Celsius(%v) = %.2f, want %.2f", u, tt.temp, got, tt.celsius)
+		}
+		if got := u.FromMetresPerSecond(tt.ms); math.Abs(got-tt.speed) > 0.01 {
+			t.Errorf("%v: FromMetresPerSecond(%v) = %.2f, want %.2f", u, tt.ms, got, tt.speed)
+		}
+		if got := u.MetresPerSecond(tt.speed); math.Abs(got-tt.ms) > 0.01 {
+			t.Errorf("%v: MetresPerSecond(%v) = %.2f, want %.2f", u, tt.speed, got, tt.ms)
+		}
+		if got := u.FromMillimetres(tt.mm); math.Abs(got-tt.precip) > 0.01 {
+			t.Errorf("%v: FromMillimetres(%v) = %.2f, want %.2f", u, tt.mm, got, tt.precip)
+		}
+		if got := u.Millimetres(tt.precip); math.Abs(got-tt.mm) > 0.01 {
+			t.Errorf("%v: Millimetres(%v) = %.2f, want %.2f", u, tt.precip, got, tt.mm)
+		}
+		if got := u.TemperatureUnit(); got != tt.tempUnit {
+			t.Errorf("%v: TemperatureUnit() = %q, want %q", u, got, tt.tempUnit)
+		}
+		if got := u.PrecipitationUnit(); got != tt.precipUnit {
+			t.Errorf("%v: PrecipitationUnit() = %q, want %q", u, got, tt.precipUnit)
+		}
+	}
+}
+
+func TestUnitLabelsHaveGlyphs(t *testing.T) {
+	for _, u := range []Units{UNITS_METRIC, UNITS_IMPERIAL, UNITS_SI} {
+		for _, c := range u.TemperatureUnit() + u.PrecipitationUnit() {
+			if _, ok := GLYPHS[c]; !ok {
+				t.Errorf("%v: no glyph for %q", u, c)
+			}
+		}
+	}
+}
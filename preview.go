@@ -0,0 +1,110 @@
+package weatherlandscape
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	PREVIEW_HALFBLOCK = iota
+	PREVIEW_BRAILLE
+)
+
+func ParsePreview(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "", "halfblock", "block":
+		return PREVIEW_HALFBLOCK, nil
+	case "braille":
+		return PREVIEW_BRAILLE, nil
+	}
+	return PREVIEW_HALFBLOCK, fmt.Errorf("unknown preview mode '%s'", name)
+}
+
+// TerminalWidth returns the width in columns of the terminal on stdout,
+// falling back to $COLUMNS and then 80 when stdout is not a terminal.
+func TerminalWidth() int {
+	if n, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && n > 0 {
+		return n
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// braille dot bits by position in the 2x4 cell, see U+2800.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// WritePreview prints a 1-bit image with Unicode block characters, two
+// pixels per character cell with half blocks or eight with braille. The
+// image is shrunk by a whole factor until it fits into cols; a scaled
+// pixel is ink if any pixel it covers is, so thin lines survive.
+func WritePreview(w io.Writer, img *image.Gray, cols, mode int) error {
+	cellW, cellH := 1, 2
+	if mode == PREVIEW_BRAILLE {
+		cellW, cellH = 2, 4
+	}
+
+	b := img.Bounds()
+	scale := 1
+	if cols > 0 {
+		for (b.Dx()+scale*cellW-1)/(scale*cellW) > cols {
+			scale++
+		}
+	}
+
+	ink := func(px, py int) bool {
+		for y := py * scale; y < (py+1)*scale; y++ {
+			for x := px * scale; x < (px+1)*scale; x++ {
+				if x < b.Dx() && y < b.Dy() && img.GrayAt(b.Min.X+x, b.Min.Y+y).Y < 0x80 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	sw := (b.Dx() + scale - 1) / scale
+	sh := (b.Dy() + scale - 1) / scale
+	bw := bufio.NewWriter(w)
+	for cy := 0; cy < sh; cy += cellH {
+		for cx := 0; cx < sw; cx += cellW {
+			if mode == PREVIEW_BRAILLE {
+				r := rune(0x2800)
+				for dy := 0; dy < cellH; dy++ {
+					for dx := 0; dx < cellW; dx++ {
+						if ink(cx+dx, cy+dy) {
+							r |= brailleDots[dy][dx]
+						}
+					}
+				}
+				bw.WriteRune(r)
+				continue
+			}
+			switch top, bottom := ink(cx, cy), ink(cx, cy+1); {
+			case top && bottom:
+				bw.WriteRune('█')
+			case top:
+				bw.WriteRune('▀')
+			case bottom:
+				bw.WriteRune('▄')
+			default:
+				bw.WriteRune(' ')
+			}
+		}
+		bw.WriteRune('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,71 @@
+package weatherlandscape
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"golang.org/x/term"
+)
+
+func TestTerminalWidthFallback(t *testing.T) {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		t.Skip("stdout is a terminal")
+	}
+	tests := []struct {
+		columns string
+		want    int
+	}{
+		{"120", 120},
+		{"", 80},
+		{"wide", 80},
+		{"-5", 80},
+	}
+	for _, tt := range tests {
+		t.Setenv("COLUMNS", tt.columns)
+		if got := TerminalWidth(); got != tt.want {
+			t.Errorf("COLUMNS=%q: TerminalWidth() = %d, want %d", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestWritePreview(t *testing.T) {
+	rows := []string{
+		"#..#",
+		"#.#.",
+		"....",
+		".##.",
+	}
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y, row := range rows {
+		for x, c := range row {
+			img.SetGray(x, y, color.Gray{0xFF})
+			if c == '#' {
+				img.SetGray(x, y, color.Gray{0})
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		mode, cols int
+		want       string
+	}{
+		{"half blocks", PREVIEW_HALFBLOCK, 0, "█ ▄▀\n ▄▄ \n"},
+		{"half blocks that fit", PREVIEW_HALFBLOCK, 4, "█ ▄▀\n ▄▄ \n"},
+		{"half blocks shrunk", PREVIEW_HALFBLOCK, 2, "██\n"},
+		{"braille", PREVIEW_BRAILLE, 0, "\u2883\u284A\n"},
+		{"braille shrunk", PREVIEW_BRAILLE, 1, "\u281B\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WritePreview(&buf, img, tt.cols, tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,14 +1,24 @@
+//go:build ignore
+
+// The web server for the E-Ink display, run with: go run runserver.go
 package main
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
 	"fmt"
-	"image"
+	"image/gif"
+	"io"
 	"net/http"
 	"os"
-	"path/filepath"
+	"sync"
 	"time"
-	"image/jpeg"
-	"weatherlandscape" // Import your package that contains WeatherLandscape
+
+	"golang.org/x/image/bmp"
+
+	"weatherlandscape"
+	"weatherlandscape/p_weather"
 )
 
 const (
@@ -16,10 +26,23 @@ const (
 	SERV_PORT        = 3355
 	EINKFILENAME     = "test.bmp"
 	USERFILENAME     = "test1.bmp"
+	EINKBWRFILENAME  = "test_bwr.bin"
+	EINKGREYFILENAME = "test_4g.bin"
+	TIMELAPSEFILENAME = "timelapse.gif"
+	TIMELAPSE_HOURS  = 24
+	TIMELAPSE_DELAY  = 50 // hundredths of a second per hour
+	ACCURACYPATH     = "/accuracy.json"
+	SENSORPATH       = "/sensor"
 	FILETOOOLD_SEC   = 60 * 10
 )
 
-var WEATHER = weatherlandscape.NewWeatherLandscape() // Assuming a constructor
+var WEATHER = weatherlandscape.NewWeatherLandscape()
+
+var (
+	MQTT      *weatherlandscape.MQTTBridge
+	imageETag string
+	etagLock  sync.RWMutex // requests read the ETag while an update writes it
+)
 
 func isFileTooOld(filename string) bool {
 	info, err := os.Stat(filename)
@@ -29,29 +52,108 @@ func isFileTooOld(filename string) bool {
 	return time.Since(info.ModTime()) > FILETOOOLD_SEC*time.Second
 }
 
-func createWeatherImages() {
+func createWeatherImages() error {
 	userFileName := WEATHER.TmpFilePath(USERFILENAME)
 	einkFileName := WEATHER.TmpFilePath(EINKFILENAME)
 
 	if !isFileTooOld(userFileName) {
-		return
+		return nil
+	}
+
+	img, ws, err := WEATHER.RenderImage()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := os.WriteFile(userFileName, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	etag := fmt.Sprintf("\"%x\"", sha1.Sum(buf.Bytes()))
+	etagLock.Lock()
+	imageETag = etag
+	etagLock.Unlock()
+
+	// the panel takes the image turned on its side
+	buf.Reset()
+	if err := weatherlandscape.EncodeEinkBMP(&buf, weatherlandscape.EinkImage(img)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(einkFileName, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if WEATHER.TRICOLOR {
+		black, red := weatherlandscape.EinkPlanes(img)
+		if err := os.WriteFile(WEATHER.TmpFilePath(EINKBWRFILENAME), append(black, red...), 0644); err != nil {
+			return err
+		}
+	}
+
+	if WEATHER.GREYLEVELS == 4 {
+		if err := os.WriteFile(WEATHER.TmpFilePath(EINKGREYFILENAME), weatherlandscape.EinkGrey2(img), 0644); err != nil {
+			return err
+		}
 	}
 
-	img := WEATHER.MakeImage() // Assuming MakeImage returns an image.Image
+	if MQTT != nil {
+		if err := MQTT.PublishRender(weatherlandscape.NewRenderEvent(etag, img, ws)); err != nil {
+			fmt.Println("MQTT:", err)
+		}
+	}
+	return nil
+}
+
+// saveSensorReading keeps the last reading a device POSTed, as JSON like
+// {"temp": 21.5, "humidity": 40, "pressure": 1013} or a bare temperature,
+// for the renderer to use as the current conditions.
+func saveSensorReading(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a sensor reading", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := p_weather.ParseReading(data); err != nil {
+		http.Error(w, "not a sensor reading: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := os.WriteFile(WEATHER.TmpFilePath(WEATHER.SENSOR_FILENAME), data, 0644); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	// Save image to file (JPEG as an example)
-	file, _ := os.Create(userFileName)
-	defer file.Close()
-	jpeg.Encode(file, img, nil)
+func createTimeLapse() error {
+	fileName := WEATHER.TmpFilePath(TIMELAPSEFILENAME)
+	if !isFileTooOld(fileName) {
+		return nil
+	}
 
-	// Rotate and flip the image if required
-	// Save the transformed image as einkFileName
+	anim, err := WEATHER.MakeTimeLapse(TIMELAPSE_HOURS, TIMELAPSE_DELAY)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
 }
 
 func indexHtml() string {
 	body := "<h1>Weather as Landscape</h1>"
-	body += fmt.Sprintf("<p>Place: %.4f, %.4f</p>", WEATHER.Lat, WEATHER.Lon)
+	body += fmt.Sprintf("<p>Place: %.4f, %.4f</p>", WEATHER.OWM_LAT, WEATHER.OWM_LON)
 	body += "<p><img src=\"" + USERFILENAME + "\" alt=\"Weather\"></p>"
+	body += fmt.Sprintf("<p><a href=\"%s\">Next %d hours</a></p>", TIMELAPSEFILENAME, TIMELAPSE_HOURS)
 	body += "<p>ESP32 URL: <span id=\"eink\"></span></p>"
 	body += "<script>document.getElementById(\"eink\").innerHTML = window.location+\"" + EINKFILENAME + "\";</script>"
 
@@ -77,8 +179,59 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Path == "/"+EINKBWRFILENAME || r.URL.Path == "/"+EINKGREYFILENAME {
+		if err := createWeatherImages(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fileName := WEATHER.TmpFilePath(r.URL.Path[1:])
+		if _, err := os.Stat(fileName); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeFile(w, r, fileName)
+		return
+	}
+
+	if r.URL.Path == "/"+TIMELAPSEFILENAME {
+		if err := createTimeLapse(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/gif")
+		http.ServeFile(w, r, WEATHER.TmpFilePath(TIMELAPSEFILENAME))
+		return
+	}
+
+	if r.URL.Path == SENSORPATH && WEATHER.SENSOR_PUSH {
+		saveSensorReading(w, r)
+		return
+	}
+
+	if r.URL.Path == ACCURACYPATH {
+		stats, err := WEATHER.Accuracy()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"units": WEATHER.OWM_UNITS,
+			"stats": stats,
+		})
+		return
+	}
+
 	if r.URL.Path == "/"+EINKFILENAME || r.URL.Path == "/"+USERFILENAME {
-		createWeatherImages()
+		if err := createWeatherImages(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
 		fileName := WEATHER.TmpFilePath(r.URL.Path[1:])
 		file, err := os.Open(fileName)
@@ -88,12 +241,26 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		}
 		defer file.Close()
 
-		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Type", "image/bmp")
+		etagLock.RLock()
+		etag := imageETag
+		etagLock.RUnlock()
+		if r.URL.Path == "/"+USERFILENAME && etag != "" {
+			w.Header().Set("ETag", etag)
+		}
 		http.ServeFile(w, r, fileName)
 	}
 }
 
 func main() {
+	if WEATHER.MQTT_BROKER != "" {
+		var err error
+		MQTT, err = weatherlandscape.NewMQTTBridge(WEATHER.MQTT_BROKER, WEATHER.MQTT_PREFIX, WEATHER.MQTT_SENSORS, WEATHER.TmpFilePath(WEATHER.SENSOR_FILENAME))
+		if err != nil {
+			fmt.Println("MQTT:", err)
+		}
+	}
+
 	http.HandleFunc("/", handler)
 	address := fmt.Sprintf("%s:%d", SERV_IPADDR, SERV_PORT)
 	fmt.Printf("Serving at http://%s/\n", address)
@@ -1,20 +1,78 @@
+//go:build ignore
+
+// Renders the landscape once, run with: go run runtest.go
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"time"
+
+	"weatherlandscape"
+	"weatherlandscape/p_weather"
 )
 
-type WeatherLandscape struct{}
+func main() {
+	preview := flag.String("preview", "", "print to the terminal instead of saving: halfblock or braille")
+	cols := flag.Int("cols", 0, "preview width in columns, defaults to the terminal width, then $COLUMNS")
+	svg := flag.Bool("svg", false, "save an SVG file instead of the bitmap")
+	ascii := flag.Bool("ascii", false, "print the landscape as text, one character per pixel")
+	replay := flag.String("replay", "", "render a past moment from the archive, e.g. 2026-10-18T15:00:00Z")
+	accuracy := flag.Bool("accuracy", false, "print how well the archived forecasts matched the weather")
+	flag.Parse()
 
-func (wl *WeatherLandscape) SaveImage() string {
-	// Placeholder logic for saving an image
-	fileName := "test_image.jpg"
-	fmt.Println("Image saved:", fileName)
-	return fileName
+	wl := weatherlandscape.NewWeatherLandscape()
+	if *accuracy {
+		stats, err := wl.Accuracy()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		p_weather.PrintAccuracy(os.Stdout, stats)
+		return
+	}
+	if err := run(wl, *preview, *cols, *svg, *ascii, *replay); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 }
 
-func main() {
-	w := WeatherLandscape{}
-	fileName := w.SaveImage()
-	fmt.Println("Saved", fileName)
+func run(wl *weatherlandscape.WeatherLandscape, preview string, cols int, svg, ascii bool, replay string) error {
+	if ascii {
+		return wl.WriteASCII(os.Stdout)
+	}
+	if replay != "" {
+		at, err := time.Parse(time.RFC3339, replay)
+		if err != nil {
+			return err
+		}
+		fn, err := wl.SaveReplay(at)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Saved", fn)
+		return nil
+	}
+	if preview != "" {
+		mode, err := weatherlandscape.ParsePreview(preview)
+		if err != nil {
+			return err
+		}
+		if cols == 0 {
+			cols = weatherlandscape.TerminalWidth()
+		}
+		return wl.Preview(mode, cols)
+	}
+
+	save := wl.SaveImage
+	if svg {
+		save = wl.SaveSVG
+	}
+	fn, err := save()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Saved", fn)
+	return nil
 }
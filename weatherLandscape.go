@@ -1,22 +1,46 @@
-package main
+package weatherlandscape
 
 import (
 	"fmt"
 	"image"
-	"image/jpeg"
+	"image/gif"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
+
+	"golang.org/x/image/bmp"
+
+	"weatherlandscape/p_weather"
 )
 
 type WeatherLandscape struct {
 	OWM_KEY          string
 	OWM_LAT, OWM_LON float64
+	OWM_UNITS        string
+	PROVIDERS        []string
+	HA_URL           string
+	HA_TOKEN         string
+	HA_ENTITY        string
 	TMP_DIR          string
 	OUT_FILENAME     string
 	OUT_FILEEXT      string
 	TEMPLATE_FILENAME string
 	SPRITES_DIR      string
 	DRAWOFFSET       int
+	TEMP_MODE        string
+	TRICOLOR         bool
+	GREYSCALE        bool
+	GREYLEVELS       int
+	DITHER           string
+	ARCHIVE_KEEP_DAYS     int
+	ARCHIVE_MAX_SNAPSHOTS int
+	SENSOR_FILENAME  string
+	SENSOR_PUSH      bool
+	MQTT_BROKER      string
+	MQTT_PREFIX      string
+	MQTT_SENSORS     map[string]string
 }
 
 func NewWeatherLandscape() *WeatherLandscape {
@@ -24,65 +48,316 @@ func NewWeatherLandscape() *WeatherLandscape {
 		OWM_KEY:           "",  // Replace with your API key
 		OWM_LAT:           52.196136,
 		OWM_LON:           21.007963,
+		OWM_UNITS:         "metric", // metric, imperial or si
+		PROVIDERS:         []string{p_weather.PROVIDER_OWM}, // openweathermap, homeassistant; several are blended into an ensemble
+		HA_URL:            "", // e.g. http://homeassistant.local:8123
+		HA_TOKEN:          "", // long-lived access token
+		HA_ENTITY:         "weather.home",
 		TMP_DIR:           "tmp",
 		OUT_FILENAME:      "test_",
 		OUT_FILEEXT:       ".bmp",
 		TEMPLATE_FILENAME: "p_weather/template.bmp",
 		SPRITES_DIR:       "p_weather/sprite",
 		DRAWOFFSET:        65,
+		TEMP_MODE:         "actual", // actual, feelslike or both
+		TRICOLOR:          false, // black/white/red panel
+		GREYSCALE:         true,  // shade clouds in grey, dithered or snapped to GREYLEVELS on output
+		GREYLEVELS:        2,     // 2, 4 or 256
+		DITHER:            "floyd-steinberg", // none, floyd-steinberg, atkinson or bayer
+		ARCHIVE_KEEP_DAYS:     30, // days of fetched forecasts kept for replay, 0 turns the archive off
+		ARCHIVE_MAX_SNAPSHOTS: 5000,
+		SENSOR_FILENAME:   "sensor.json", // local reading that replaces the current conditions, empty to disable
+		SENSOR_PUSH:       false, // let devices POST readings to the server's /sensor
+		MQTT_BROKER:       "", // e.g. tcp://localhost:1883, empty to disable
+		MQTT_PREFIX:       "weatherlandscape",
+		MQTT_SENSORS:      map[string]string{}, // topic -> temp, humidity or pressure
 	}
 
-	if wl.OWM_KEY == "" {
+	if wl.OWM_KEY == "" && slices.Contains(wl.PROVIDERS, p_weather.PROVIDER_OWM) {
 		panic("Set OWM_KEY variable to your OpenWeather API key")
 	}
 	return wl
 }
 
-func (wl *WeatherLandscape) MakeImage() image.Image {
-	// Replace this with actual OpenWeatherMap API call and processing logic
-	fmt.Println("Fetching weather data from OpenWeatherMap")
+// MakeImage fetches the weather and draws the landscape on the template.
+func (wl *WeatherLandscape) MakeImage() (image.Image, error) {
+	img, _, err := wl.makeImage()
+	return img, err
+}
+
+func (wl *WeatherLandscape) makeImage() (image.Image, *p_weather.WeatherSeries, error) {
+	template, err := wl.loadTemplate()
+	if err != nil {
+		return nil, nil, err
+	}
+	canvas := p_weather.NewRasterCanvas(template)
+	ws, err := wl.drawOn(canvas, template)
+	if err != nil {
+		return nil, nil, err
+	}
+	return canvas.Image(), ws, nil
+}
+
+// drawOn fetches the weather and draws the landscape on canvas, the
+// template giving the size of the picture. It returns the series drawn.
+func (wl *WeatherLandscape) drawOn(canvas p_weather.Canvas, template image.Image) (*p_weather.WeatherSeries, error) {
+	art, provider, err := wl.prepare(canvas, template)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := wl.withSensor(provider).Fetch()
+	if err != nil {
+		return nil, err
+	}
+	art.Draw(wl.DRAWOFFSET, ws)
+	return ws, nil
+}
 
-	imgFile, err := os.Open(wl.TEMPLATE_FILENAME)
+// prepare sets up the drawing on canvas and the archived weather
+// provider to draw.
+func (wl *WeatherLandscape) prepare(canvas p_weather.Canvas, template image.Image) (*p_weather.DrawWeather, *p_weather.Archive, error) {
+	tempMode, err := p_weather.ParseTempMode(wl.TEMP_MODE)
 	if err != nil {
-		panic("Failed to open template image")
+		return nil, nil, err
+	}
+	arc, _, err := wl.providers()
+	if err != nil {
+		return nil, nil, err
 	}
-	defer imgFile.Close()
 
-	img, _, err := image.Decode(imgFile)
+	spr, err := p_weather.NewSprites(wl.SPRITES_DIR, template)
 	if err != nil {
-		panic("Failed to decode image")
+		return nil, nil, err
 	}
+	spr.Canvas = canvas
+	spr.TRICOLOR = wl.TRICOLOR
+	spr.GREYSCALE = wl.GREYSCALE
+
+	art := p_weather.NewDrawWeather(template, spr)
+	art.TEMP_MODE = tempMode
+	return art, arc, nil
+}
 
-	// Simulate drawing weather data on the image
-	fmt.Println("Drawing weather data on image")
+// newProvider sets up one weather source by name.
+func (wl *WeatherLandscape) newProvider(name string, units p_weather.Units) (p_weather.Provider, error) {
+	switch name {
+	case p_weather.PROVIDER_OWM:
+		return p_weather.NewOpenWeatherMap(wl.OWM_KEY, wl.OWM_LAT, wl.OWM_LON, wl.TMP_DIR, units), nil
+	case p_weather.PROVIDER_HA:
+		if wl.HA_URL == "" || wl.HA_TOKEN == "" || wl.HA_ENTITY == "" {
+			return nil, fmt.Errorf("set HA_URL, HA_TOKEN and HA_ENTITY to use %s", name)
+		}
+		return p_weather.NewHomeAssistant(wl.HA_URL, wl.HA_TOKEN, wl.HA_ENTITY, units), nil
+	}
+	return nil, fmt.Errorf("unknown provider '%s'", name)
+}
 
-	return img
+func (wl *WeatherLandscape) archive(p p_weather.Provider) *p_weather.Archive {
+	arc := p_weather.NewArchive(p, wl.TMP_DIR, p_weather.PlaceKey(wl.OWM_LAT, wl.OWM_LON))
+	arc.KEEP_DAYS = wl.ARCHIVE_KEEP_DAYS
+	arc.MAX_SNAPSHOTS = wl.ARCHIVE_MAX_SNAPSHOTS
+	return arc
 }
 
-func (wl *WeatherLandscape) SaveImage() string {
-	img := wl.MakeImage()
-	placekey := fmt.Sprintf("%.4f_%.4f", wl.OWM_LAT, wl.OWM_LON)
-	outfilepath := wl.TmpFilePath(wl.OUT_FILENAME + placekey + wl.OUT_FILEEXT)
+// providers sets up the configured weather sources, each archived on its
+// own. Several sources are blended into an ensemble, archived as well,
+// which is the one to draw; all lists every archive.
+func (wl *WeatherLandscape) providers() (draw *p_weather.Archive, all []*p_weather.Archive, err error) {
+	units, err := p_weather.ParseUnits(wl.OWM_UNITS)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(wl.PROVIDERS) == 0 {
+		return nil, nil, fmt.Errorf("no weather provider configured")
+	}
+	members := []p_weather.Provider{}
+	seen := map[string]bool{}
+	for _, name := range wl.PROVIDERS {
+		if seen[name] {
+			return nil, nil, fmt.Errorf("provider '%s' configured twice", name)
+		}
+		seen[name] = true
+		p, err := wl.newProvider(name, units)
+		if err != nil {
+			return nil, nil, err
+		}
+		arc := wl.archive(p)
+		members = append(members, arc)
+		all = append(all, arc)
+	}
+	if len(members) == 1 {
+		return all[0], all, nil
+	}
+	draw = wl.archive(p_weather.NewEnsemble(members...))
+	return draw, append(all, draw), nil
+}
+
+// withSensor lets a recent local reading replace the provider's current
+// conditions. The archive keeps what the provider observed.
+func (wl *WeatherLandscape) withSensor(p p_weather.Provider) p_weather.Provider {
+	if wl.SENSOR_FILENAME == "" {
+		return p
+	}
+	return p_weather.NewLocalConditions(p, &p_weather.FileSensor{Path: wl.TmpFilePath(wl.SENSOR_FILENAME)})
+}
+
+// Accuracy scores the archived forecasts against the conditions
+// observed later, per provider and lead time.
+func (wl *WeatherLandscape) Accuracy() ([]*p_weather.AccuracyStats, error) {
+	_, all, err := wl.providers()
+	if err != nil {
+		return nil, err
+	}
+	return p_weather.Accuracy(all...)
+}
+
+// MakeTimeLapse animates the landscape hour by hour from now on, delay
+// hundredths of a second per frame.
+func (wl *WeatherLandscape) MakeTimeLapse(hours, delay int) (*gif.GIF, error) {
+	template, err := wl.loadTemplate()
+	if err != nil {
+		return nil, err
+	}
+	art, provider, err := wl.prepare(p_weather.NewRasterCanvas(template), template)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := wl.withSensor(provider).Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return art.TimeLapse(wl.DRAWOFFSET, ws, time.Now(), hours, delay), nil
+}
+
+// SaveReplay draws the landscape as it was shown at a past moment, from
+// the forecast archived last before it.
+func (wl *WeatherLandscape) SaveReplay(at time.Time) (string, error) {
+	dither, err := ParseDither(wl.DITHER)
+	if err != nil {
+		return "", err
+	}
+	template, err := wl.loadTemplate()
+	if err != nil {
+		return "", err
+	}
+	canvas := p_weather.NewRasterCanvas(template)
+	art, arc, err := wl.prepare(canvas, template)
+	if err != nil {
+		return "", err
+	}
+	ws, taken, err := arc.At(at)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Replaying %s, %s forecast fetched %s\n", at.Format(time.RFC3339), arc.Name(), taken.Format(time.RFC3339))
+	art.DrawAt(wl.DRAWOFFSET, ws, at)
+
+	img := Quantize(canvas.Image(), wl.GREYLEVELS, dither, wl.TRICOLOR)
+	outfilepath := wl.outFilePath("_" + at.UTC().Format(p_weather.ARCHIVE_TIME_FORMAT) + wl.OUT_FILEEXT)
+	return outfilepath, writeBMP(outfilepath, img)
+}
+
+func (wl *WeatherLandscape) loadTemplate() (image.Image, error) {
+	file, err := os.Open(wl.TEMPLATE_FILENAME)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", wl.TEMPLATE_FILENAME, err)
+	}
+	return img, nil
+}
+
+// RenderImage is MakeImage reduced to the colours of the panel. It also
+// returns the weather series drawn.
+func (wl *WeatherLandscape) RenderImage() (image.Image, *p_weather.WeatherSeries, error) {
+	dither, err := ParseDither(wl.DITHER)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, ws, err := wl.makeImage()
+	if err != nil {
+		return nil, nil, err
+	}
+	return Quantize(img, wl.GREYLEVELS, dither, wl.TRICOLOR), ws, nil
+}
 
+func (wl *WeatherLandscape) SaveImage() (string, error) {
+	img, _, err := wl.RenderImage()
+	if err != nil {
+		return "", err
+	}
+	outfilepath := wl.outFilePath(wl.OUT_FILEEXT)
+	return outfilepath, writeBMP(outfilepath, img)
+}
+
+func writeBMP(filename string, img image.Image) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return bmp.Encode(file, img)
+}
+
+// SaveSVG draws the landscape as an SVG file next to the bitmap.
+func (wl *WeatherLandscape) SaveSVG() (string, error) {
+	template, err := wl.loadTemplate()
+	if err != nil {
+		return "", err
+	}
+	b := template.Bounds()
+	canvas := p_weather.NewSVGCanvas(b.Dx(), b.Dy())
+	canvas.BlitSprite(template, 0, 0)
+	if _, err := wl.drawOn(canvas, template); err != nil {
+		return "", err
+	}
+
+	outfilepath := wl.outFilePath(".svg")
 	file, err := os.Create(outfilepath)
 	if err != nil {
-		panic("Failed to create output file")
+		return "", err
 	}
 	defer file.Close()
 
-	err = jpeg.Encode(file, img, nil)
+	if _, err := canvas.WriteTo(file); err != nil {
+		return "", err
+	}
+	return outfilepath, nil
+}
+
+// WriteASCII draws the landscape as text, one character per pixel.
+func (wl *WeatherLandscape) WriteASCII(w io.Writer) error {
+	template, err := wl.loadTemplate()
 	if err != nil {
-		panic("Failed to save image")
+		return err
+	}
+	canvas := p_weather.NewASCIICanvas(template)
+	if _, err := wl.drawOn(canvas, template); err != nil {
+		return err
 	}
-	return outfilepath
+	_, err = canvas.WriteTo(w)
+	return err
 }
 
-func (wl *WeatherLandscape) TmpFilePath(filename string) string {
-	return filepath.Join(wl.TMP_DIR, filename)
+func (wl *WeatherLandscape) outFilePath(ext string) string {
+	placekey := fmt.Sprintf("%.4f_%.4f", wl.OWM_LAT, wl.OWM_LON)
+	return wl.TmpFilePath(wl.OUT_FILENAME + placekey + ext)
 }
 
-func main() {
-	wl := NewWeatherLandscape()
-	fn := wl.SaveImage()
-	fmt.Println("Saved", fn)
+// Preview prints the 1-bit landscape to the terminal, cols wide, for
+// checking the layout without copying images off the server.
+func (wl *WeatherLandscape) Preview(mode, cols int) error {
+	img, err := wl.MakeImage()
+	if err != nil {
+		return err
+	}
+	return WritePreview(os.Stdout, Quantize(img, 2, DITHER_FLOYDSTEINBERG, false).(*image.Gray), cols, mode)
+}
+
+func (wl *WeatherLandscape) TmpFilePath(filename string) string {
+	return filepath.Join(wl.TMP_DIR, filename)
 }